@@ -1,7 +1,9 @@
 package form3go
 
 import (
+	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/pariz/gountries"
 	"golang.org/x/text/currency"
@@ -13,35 +15,157 @@ type Account struct {
 	AccountData Data `json:"data"`
 }
 
-// Data is account information
+// Data is account resource
 type Data struct {
-	Type                        string            `json:"type" validate:"type"`
-	ID                          string            `json:"id" validate:"id"`
-	OrganisationID              string            `json:"organisation_id" validate:"oid"`
-	Attributes                  AccountAttributes `json:"attributes"`
-	Title                       string            `json:"title" validate:"title"`
-	FirstName                   string            `json:"first_name" validate:"first_name"`
-	BankAccountName             string            `json:"bank_account_name" validate:"ban"`
-	AlternativeBankAccountNames []string          `json:"alternative_bank_account_names"`
-	AccountClassification       string            `json:"account_classification"`
-	JointAccount                bool              `json:"joint_account"`
-	AccountMatchingOptOut       bool              `json:"account_matching_opt_out"`
-	SecondaryIdentification     string            `json:"secondary_identification" validate:"si"`
-}
-
-// AccountAttributes is Account Attributes
+	Type           string                `json:"type" validate:"type"`
+	ID             string                `json:"id"`
+	OrganisationID string                `json:"organisation_id"`
+	Version        int                   `json:"version"`
+	CreatedOn      *time.Time            `json:"created_on,omitempty"`
+	ModifiedOn     *time.Time            `json:"modified_on,omitempty"`
+	Attributes     AccountAttributes     `json:"attributes"`
+	Relationships  *AccountRelationships `json:"relationships,omitempty"`
+}
+
+// AccountAttributes is Account Attributes. Optional attributes are
+// omitted on create when empty. Booleans, classification and status are
+// pointers so that unset values are not sent as false or empty.
 type AccountAttributes struct {
-	Country       string `json:"country" validate:"country"`
-	BaseCurrency  string `json:"base_currency" validate:"currency"`
-	AccountNumber string `json:"account_number" validate:"number"`
-	BankID        string `json:"bank_id" validate:"bank_id"`
-	BankIDCode    string `json:"bank_id_code" validate:"bank_id_code"`
-	BIC           string `json:"bic" validate:"bic"`
-	IBAN          string `json:"iban" validate:"iban"`
+	Country                     string            `json:"country" validate:"country"`
+	BaseCurrency                string            `json:"base_currency,omitempty" validate:"currency"`
+	AccountNumber               string            `json:"account_number,omitempty" validate:"number"`
+	BankID                      string            `json:"bank_id,omitempty" validate:"bank_id"`
+	BankIDCode                  string            `json:"bank_id_code,omitempty" validate:"bank_id_code"`
+	BIC                         string            `json:"bic,omitempty" validate:"bic"`
+	IBAN                        string            `json:"iban,omitempty" validate:"iban"`
+	Name                        []string          `json:"name,omitempty" validate:"max=4,dive,ban"`
+	AlternativeNames            []string          `json:"alternative_names,omitempty" validate:"max=3,dive,ban"`
+	Title                       string            `json:"title,omitempty" validate:"title"`
+	FirstName                   string            `json:"first_name,omitempty" validate:"first_name"`
+	BankAccountName             string            `json:"bank_account_name,omitempty" validate:"ban"`
+	AlternativeBankAccountNames []string          `json:"alternative_bank_account_names,omitempty" validate:"max=3,dive,ban"`
+	AccountClassification       *string           `json:"account_classification,omitempty" validate:"omitempty,oneof=Personal Business"`
+	JointAccount                *bool             `json:"joint_account,omitempty"`
+	AccountMatchingOptOut       *bool             `json:"account_matching_opt_out,omitempty"`
+	SecondaryIdentification     string            `json:"secondary_identification,omitempty" validate:"si"`
+	Switched                    *bool             `json:"switched,omitempty"`
+	Status                      *string           `json:"status,omitempty" validate:"omitempty,oneof=pending confirmed failed closed"`
+	StatusReason                string            `json:"status_reason,omitempty"`
+	UserDefinedData             []UserDefinedData `json:"user_defined_data,omitempty" validate:"max=5,dive"`
+	ValidationType              string            `json:"validation_type,omitempty" validate:"omitempty,oneof=card"`
+	ReferenceMask               string            `json:"reference_mask,omitempty" validate:"max=35"`
+	AcceptanceQualifier         string            `json:"acceptance_qualifier,omitempty" validate:"omitempty,oneof=same_day next_day"`
+	ProcessingService           string            `json:"processing_service,omitempty" validate:"max=35"`
+	UserDefinedInformation      string            `json:"user_defined_information,omitempty" validate:"max=140"`
+}
+
+// UserDefinedData is key value pair stored with account
+type UserDefinedData struct {
+	Key   string `json:"key" validate:"required,max=50"`
+	Value string `json:"value" validate:"max=1000"`
+}
+
+// AccountRelationships is related resources of account
+type AccountRelationships struct {
+	MasterAccount *Relationship `json:"master_account,omitempty"`
+	AccountEvents *Relationship `json:"account_events,omitempty"`
+}
+
+// Relationship is list of related resources
+type Relationship struct {
+	Data []ResourceIdentifier `json:"data"`
+}
+
+// ResourceIdentifier identifies related resource
+type ResourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// ValidateOptions configure Account.ValidateWithOptions.
+type ValidateOptions struct {
+	// IDVersions are accepted UUID versions of account ID,
+	// DefaultIDVersions if empty.
+	IDVersions []int
 }
 
 // Validate validates Account fields
 func (a Account) Validate() error {
+	return a.ValidateWithOptions(ValidateOptions{})
+}
+
+// ValidateWithOptions validates Account fields, accepting account IDs of
+// UUID versions given in opts. Organisation ID may be of any version.
+func (a Account) ValidateWithOptions(opts ValidateOptions) error {
+	versions := opts.IDVersions
+	if len(versions) == 0 {
+		versions = DefaultIDVersions
+	}
+	if err := validateUUID(a.AccountData.ID, versions); err != nil {
+		return fmt.Errorf("form3go: invalid account ID: %v", err)
+	}
+	if oid := a.AccountData.OrganisationID; oid != "" {
+		if err := validateUUID(oid, nil); err != nil {
+			return fmt.Errorf("form3go: invalid organisation ID: %v", err)
+		}
+	}
+	return newAccountValidator().Struct(a)
+}
+
+// AccountPatch is partial update of account attributes sent by
+// UpdateAccount. Only non-nil fields are sent and validated, with the
+// same rules as Account.Validate.
+type AccountPatch struct {
+	Country                     *string            `json:"country,omitempty" validate:"omitempty,country"`
+	BaseCurrency                *string            `json:"base_currency,omitempty" validate:"omitempty,currency"`
+	AccountNumber               *string            `json:"account_number,omitempty" validate:"omitempty,number"`
+	BankID                      *string            `json:"bank_id,omitempty" validate:"omitempty,bank_id"`
+	BankIDCode                  *string            `json:"bank_id_code,omitempty" validate:"omitempty,bank_id_code"`
+	BIC                         *string            `json:"bic,omitempty" validate:"omitempty,bic"`
+	IBAN                        *string            `json:"iban,omitempty" validate:"omitempty,iban"`
+	Name                        *[]string          `json:"name,omitempty" validate:"omitempty,max=4,dive,ban"`
+	AlternativeNames            *[]string          `json:"alternative_names,omitempty" validate:"omitempty,max=3,dive,ban"`
+	Title                       *string            `json:"title,omitempty" validate:"omitempty,title"`
+	FirstName                   *string            `json:"first_name,omitempty" validate:"omitempty,first_name"`
+	BankAccountName             *string            `json:"bank_account_name,omitempty" validate:"omitempty,ban"`
+	AlternativeBankAccountNames *[]string          `json:"alternative_bank_account_names,omitempty" validate:"omitempty,max=3,dive,ban"`
+	AccountClassification       *string            `json:"account_classification,omitempty" validate:"omitempty,oneof=Personal Business"`
+	JointAccount                *bool              `json:"joint_account,omitempty"`
+	AccountMatchingOptOut       *bool              `json:"account_matching_opt_out,omitempty"`
+	SecondaryIdentification     *string            `json:"secondary_identification,omitempty" validate:"omitempty,si"`
+	Switched                    *bool              `json:"switched,omitempty"`
+	Status                      *string            `json:"status,omitempty" validate:"omitempty,oneof=pending confirmed failed closed"`
+	StatusReason                *string            `json:"status_reason,omitempty"`
+	UserDefinedData             *[]UserDefinedData `json:"user_defined_data,omitempty" validate:"omitempty,max=5,dive"`
+	ValidationType              *string            `json:"validation_type,omitempty" validate:"omitempty,oneof=card"`
+	ReferenceMask               *string            `json:"reference_mask,omitempty" validate:"omitempty,max=35"`
+	AcceptanceQualifier         *string            `json:"acceptance_qualifier,omitempty" validate:"omitempty,oneof=same_day next_day"`
+	ProcessingService           *string            `json:"processing_service,omitempty" validate:"omitempty,max=35"`
+	UserDefinedInformation      *string            `json:"user_defined_information,omitempty" validate:"omitempty,max=140"`
+}
+
+// Validate validates AccountPatch fields
+func (p AccountPatch) Validate() error {
+	return newAccountValidator().Struct(p)
+}
+
+// isEmpty reports whether p changes nothing.
+func (p AccountPatch) isEmpty() bool {
+	return p == AccountPatch{}
+}
+
+// String returns pointer to s, e.g. for AccountPatch fields.
+func String(s string) *string {
+	return &s
+}
+
+// Bool returns pointer to b, e.g. for AccountPatch fields.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// newAccountValidator returns validator of account fields.
+func newAccountValidator() *validator.Validate {
 	v := validator.New()
 	_ = v.RegisterValidation("country", func(fl validator.FieldLevel) bool {
 		query := gountries.New()
@@ -155,24 +279,5 @@ func (a Account) Validate() error {
 		}
 		return true
 	})
-	_ = v.RegisterValidation("id", func(fl validator.FieldLevel) bool {
-		id := fl.Field().String()
-		r := regexp.MustCompile("^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$")
-		if r.MatchString(id) {
-			return true
-		}
-		return false
-	})
-	_ = v.RegisterValidation("oid", func(fl validator.FieldLevel) bool {
-		oid := fl.Field().String()
-		if oid != "" {
-			r := regexp.MustCompile("^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$")
-			if !r.MatchString(oid) {
-				return false
-			}
-		}
-		return true
-	})
-
-	return v.Struct(a)
+	return v
 }
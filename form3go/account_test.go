@@ -2,7 +2,9 @@ package form3go
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -27,7 +29,7 @@ var (
 			"alternative_bank_account_names": [
 				"Sam Holder"
 			],
-			"account_classification": "Persoasdsdfsd234234",
+			"account_classification": "Personal",
 			"joint_account": false,
 			"account_matching_opt_out": false,
 			"secondary_identification": "x1B2C3D4"
@@ -36,17 +38,39 @@ var (
 	  }`
 )
 
+// withoutServerFields returns accounts with fields set by server
+// cleared, so that they compare equal to requested ones.
+func withoutServerFields(accounts ...Account) []Account {
+	cleared := make([]Account, len(accounts))
+	for i, acct := range accounts {
+		acct.AccountData.Version = 0
+		acct.AccountData.CreatedOn = nil
+		acct.AccountData.ModifiedOn = nil
+		cleared[i] = acct
+	}
+	return cleared
+}
+
 func TestAccountValidate(t *testing.T) {
 	account := &Account{}
 	_ = json.Unmarshal([]byte(testAccountInfo), account)
 
 	// Empty ID is provided
 	account.AccountData.ID = ""
-	assert.Equal(t, "Key: 'Account.AccountData.ID' Error:Field validation for 'ID' failed on the 'id' tag", account.Validate().Error())
+	assert.Equal(t, "form3go: invalid account ID: UUID is empty", account.Validate().Error())
 
 	// Invalid UUID is provided for ID
 	account.AccountData.ID = "127e265-9605-4b4b-a0e5-3003ea9cc4dc"
-	assert.Equal(t, "Key: 'Account.AccountData.ID' Error:Field validation for 'ID' failed on the 'id' tag", account.Validate().Error())
+	assert.Equal(t, `form3go: invalid account ID: "127e265-9605-4b4b-a0e5-3003ea9cc4dc" is not UUID in 8-4-4-4-12 hex digits form`, account.Validate().Error())
+
+	// UUID of other variant is provided for ID
+	account.AccountData.ID = "9127e265-9605-4b4b-c0e5-3003ea9cc4dc"
+	assert.Equal(t, `form3go: invalid account ID: UUID "9127e265-9605-4b4b-c0e5-3003ea9cc4dc" is not of RFC 9562 variant`, account.Validate().Error())
+
+	// UUID of other version is provided for ID
+	account.AccountData.ID = "9127e265-9605-1b4b-a0e5-3003ea9cc4dc"
+	assert.Equal(t, `form3go: invalid account ID: UUID "9127e265-9605-1b4b-a0e5-3003ea9cc4dc" is version 1, accepted versions are 4, 5, 7`, account.Validate().Error())
+	assert.Nil(t, account.ValidateWithOptions(ValidateOptions{IDVersions: []int{1}}))
 
 	// Invalid Country Code is provided
 	account.AccountData.ID = "9127e265-9605-4b4b-a0e5-3003ea9cc4dc"
@@ -80,7 +104,7 @@ func TestAccountValidate(t *testing.T) {
 	// Invalid UUID is provided for OrganisationID
 	account.AccountData.Attributes.IBAN = "GL11NWBK40030041426819"
 	account.AccountData.OrganisationID = "b0bd6f5-c3f5-44b2-b677-acd23cdde73c"
-	assert.Equal(t, "Key: 'Account.AccountData.OrganisationID' Error:Field validation for 'OrganisationID' failed on the 'oid' tag", account.Validate().Error())
+	assert.Equal(t, `form3go: invalid organisation ID: "b0bd6f5-c3f5-44b2-b677-acd23cdde73c" is not UUID in 8-4-4-4-12 hex digits form`, account.Validate().Error())
 
 	// All Valid informations
 	account.AccountData.ID = "9127e265-9605-4b4b-a0e5-3003ea9cc4dc"
@@ -88,6 +112,88 @@ func TestAccountValidate(t *testing.T) {
 	assert.Nil(t, account.Validate())
 
 	// Invalid Long Title is provided
-	account.AccountData.Title = "This string is for testing which will take error because it will be longer than 40 characters"
-	assert.Equal(t, "Key: 'Account.AccountData.Title' Error:Field validation for 'Title' failed on the 'title' tag", account.Validate().Error())
+	account.AccountData.Attributes.Title = "This string is for testing which will take error because it will be longer than 40 characters"
+	assert.Equal(t, "Key: 'Account.AccountData.Attributes.Title' Error:Field validation for 'Title' failed on the 'title' tag", account.Validate().Error())
+
+	// Invalid classification is provided
+	account.AccountData.Attributes.Title = ""
+	account.AccountData.Attributes.AccountClassification = String("Persoasdsdfsd234234")
+	assert.Equal(t, "Key: 'Account.AccountData.Attributes.AccountClassification' Error:Field validation for 'AccountClassification' failed on the 'oneof' tag", account.Validate().Error())
+
+	// Too many names are provided
+	account.AccountData.Attributes.AccountClassification = String("Business")
+	account.AccountData.Attributes.Name = []string{"Samantha", "Holder", "Sam", "Holder", "Jr"}
+	assert.Equal(t, "Key: 'Account.AccountData.Attributes.Name' Error:Field validation for 'Name' failed on the 'max' tag", account.Validate().Error())
+	account.AccountData.Attributes.Name = []string{"Samantha Holder"}
+	assert.Nil(t, account.Validate())
+}
+
+func TestAccountPatchValidate(t *testing.T) {
+	// Unset fields are not validated
+	assert.Nil(t, AccountPatch{}.Validate())
+	assert.Nil(t, AccountPatch{Country: String("GB"), BIC: String("NWBKGB22"), JointAccount: Bool(true)}.Validate())
+
+	// Set fields are validated with Account rules
+	assert.Equal(t, "Key: 'AccountPatch.Country' Error:Field validation for 'Country' failed on the 'country' tag", AccountPatch{Country: String("342")}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.IBAN' Error:Field validation for 'IBAN' failed on the 'iban' tag", AccountPatch{IBAN: String("Gi11NWBK40030041426819")}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.Title' Error:Field validation for 'Title' failed on the 'title' tag", AccountPatch{Title: String(strings.Repeat("a", 41))}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.ValidationType' Error:Field validation for 'ValidationType' failed on the 'oneof' tag", AccountPatch{ValidationType: String("iban")}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.AcceptanceQualifier' Error:Field validation for 'AcceptanceQualifier' failed on the 'oneof' tag", AccountPatch{AcceptanceQualifier: String("never")}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.ReferenceMask' Error:Field validation for 'ReferenceMask' failed on the 'max' tag", AccountPatch{ReferenceMask: String(strings.Repeat("#", 36))}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.ProcessingService' Error:Field validation for 'ProcessingService' failed on the 'max' tag", AccountPatch{ProcessingService: String(strings.Repeat("a", 36))}.Validate().Error())
+	assert.Equal(t, "Key: 'AccountPatch.UserDefinedInformation' Error:Field validation for 'UserDefinedInformation' failed on the 'max' tag", AccountPatch{UserDefinedInformation: String(strings.Repeat("a", 141))}.Validate().Error())
+	assert.NotNil(t, AccountPatch{UserDefinedData: &[]UserDefinedData{{Key: "k"}, {Key: "k"}, {Key: "k"}, {Key: "k"}, {Key: "k"}, {Key: "k"}}}.Validate())
+	assert.NotNil(t, AccountPatch{UserDefinedData: &[]UserDefinedData{{Value: "missing key"}}}.Validate())
+	assert.Nil(t, AccountPatch{
+		UserDefinedData:     &[]UserDefinedData{{Key: "Some account key", Value: "Some account value"}},
+		ValidationType:      String("card"),
+		AcceptanceQualifier: String("same_day"),
+		ReferenceMask:       String("############"),
+	}.Validate())
+
+	// empty user defined data clears it
+	data, err := json.Marshal(AccountPatch{UserDefinedData: &[]UserDefinedData{}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"user_defined_data":[]}`, string(data))
+}
+
+func TestAccountVersion(t *testing.T) {
+	account := Account{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"data":{"version":3,"created_on":"2021-04-20T10:15:00.123Z","modified_on":"2021-04-21T08:00:00Z"}}`), &account))
+	assert.Equal(t, 3, account.AccountData.Version)
+	assert.Equal(t, time.Date(2021, 4, 20, 10, 15, 0, 123000000, time.UTC), *account.AccountData.CreatedOn)
+	assert.Equal(t, time.Date(2021, 4, 21, 8, 0, 0, 0, time.UTC), *account.AccountData.ModifiedOn)
+
+	// timestamps are set by server
+	data, err := json.Marshal(Account{})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "created_on")
+}
+
+func TestAccountJSON(t *testing.T) {
+	// attributes round-trip
+	account := Account{}
+	assert.Nil(t, json.Unmarshal([]byte(testAccountInfo), &account))
+	attrs := account.AccountData.Attributes
+	assert.Equal(t, "23fdc&", attrs.Title)
+	assert.Equal(t, "Samantha", attrs.FirstName)
+	assert.Equal(t, []string{"Sam Holder"}, attrs.AlternativeBankAccountNames)
+	assert.Equal(t, "Personal", *attrs.AccountClassification)
+	assert.False(t, *attrs.JointAccount)
+	data, err := json.Marshal(account)
+	assert.Nil(t, err)
+	var encoded, fixture map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &encoded))
+	assert.Nil(t, json.Unmarshal([]byte(testAccountInfo), &fixture))
+	assert.Equal(t, fixture["data"].(map[string]interface{})["attributes"], encoded["data"].(map[string]interface{})["attributes"])
+
+	// absent attributes are omitted
+	data, err = json.Marshal(AccountAttributes{Country: "GB", JointAccount: Bool(false)})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"country":"GB","joint_account":false}`, string(data))
+
+	// relationships
+	assert.Nil(t, json.Unmarshal([]byte(`{"data":{"relationships":{"master_account":{"data":[{"type":"accounts","id":"a52d13a4-f435-4c00-cfad-f5e7ac5972df"}]}}}}`), &account))
+	assert.Equal(t, []ResourceIdentifier{{Type: "accounts", ID: "a52d13a4-f435-4c00-cfad-f5e7ac5972df"}}, account.AccountData.Relationships.MasterAccount.Data)
+	assert.Nil(t, account.AccountData.Relationships.AccountEvents)
 }
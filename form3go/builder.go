@@ -0,0 +1,116 @@
+package form3go
+
+// AccountBuilder builds Account with generated ID, see NewAccountBuilder.
+type AccountBuilder struct {
+	acct  Account
+	newID func() (string, error)
+}
+
+// NewAccountBuilder returns builder of account of organisation in
+// country. Unless ID is set, random UUID version 4 ID is generated by
+// Build:
+//
+//	acct, err := form3go.NewAccountBuilder(organisationID, "GB").
+//		BankID("400300", "GBDSC").
+//		BIC("NWBKGB22").
+//		Name("Samantha Holder").
+//		Build()
+func NewAccountBuilder(organisationID, country string) *AccountBuilder {
+	b := &AccountBuilder{newID: NewAccountID}
+	b.acct.AccountData.Type = "accounts"
+	b.acct.AccountData.OrganisationID = organisationID
+	b.acct.AccountData.Attributes.Country = country
+	return b
+}
+
+// ID sets account ID.
+func (b *AccountBuilder) ID(id string) *AccountBuilder {
+	b.newID = func() (string, error) { return id, nil }
+	return b
+}
+
+// TimeOrderedID makes Build generate UUID version 7 ID, see
+// NewAccountIDV7.
+func (b *AccountBuilder) TimeOrderedID() *AccountBuilder {
+	b.newID = NewAccountIDV7
+	return b
+}
+
+// IDFromKey makes Build derive ID from business key in namespace of
+// organisation ID, see AccountIDFromKey.
+func (b *AccountBuilder) IDFromKey(key string) *AccountBuilder {
+	b.newID = func() (string, error) {
+		return AccountIDFromKey(b.acct.AccountData.OrganisationID, key)
+	}
+	return b
+}
+
+// BaseCurrency sets ISO 4217 base currency.
+func (b *AccountBuilder) BaseCurrency(currency string) *AccountBuilder {
+	b.acct.AccountData.Attributes.BaseCurrency = currency
+	return b
+}
+
+// BankID sets bank ID and bank ID code, e.g. "GBDSC" for UK sort code.
+func (b *AccountBuilder) BankID(bankID, bankIDCode string) *AccountBuilder {
+	b.acct.AccountData.Attributes.BankID = bankID
+	b.acct.AccountData.Attributes.BankIDCode = bankIDCode
+	return b
+}
+
+// BIC sets SWIFT BIC.
+func (b *AccountBuilder) BIC(bic string) *AccountBuilder {
+	b.acct.AccountData.Attributes.BIC = bic
+	return b
+}
+
+// AccountNumber sets account number.
+func (b *AccountBuilder) AccountNumber(number string) *AccountBuilder {
+	b.acct.AccountData.Attributes.AccountNumber = number
+	return b
+}
+
+// IBAN sets IBAN.
+func (b *AccountBuilder) IBAN(iban string) *AccountBuilder {
+	b.acct.AccountData.Attributes.IBAN = iban
+	return b
+}
+
+// Name sets name of account holder, up to four lines.
+func (b *AccountBuilder) Name(name ...string) *AccountBuilder {
+	b.acct.AccountData.Attributes.Name = name
+	return b
+}
+
+// Classification sets account classification, "Personal" or "Business".
+func (b *AccountBuilder) Classification(classification string) *AccountBuilder {
+	b.acct.AccountData.Attributes.AccountClassification = String(classification)
+	return b
+}
+
+// JointAccount sets whether account is joint account.
+func (b *AccountBuilder) JointAccount(joint bool) *AccountBuilder {
+	b.acct.AccountData.Attributes.JointAccount = Bool(joint)
+	return b
+}
+
+// Attributes applies f to attributes of account, for attributes without
+// builder method.
+func (b *AccountBuilder) Attributes(f func(*AccountAttributes)) *AccountBuilder {
+	f(&b.acct.AccountData.Attributes)
+	return b
+}
+
+// Build returns validated account with ID set or generated.
+func (b *AccountBuilder) Build() (Account, error) {
+	id, err := b.newID()
+	if err != nil {
+		return Account{}, err
+	}
+	acct := b.acct
+	acct.AccountData.ID = id
+	if err := acct.Validate(); err != nil {
+		return Account{}, err
+	}
+	return acct, nil
+}
@@ -0,0 +1,65 @@
+package form3go
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountBuilder(t *testing.T) {
+	orgID := "db0bd6f5-c3f5-44b2-b677-acd23cdde73c"
+	acct, err := NewAccountBuilder(orgID, "GB").
+		BaseCurrency("GBP").
+		BankID("400300", "GBDSC").
+		BIC("NWBKGB22").
+		AccountNumber("41426819").
+		IBAN("GB11NWBK40030041426819").
+		Name("Samantha Holder").
+		Classification("Personal").
+		JointAccount(false).
+		Attributes(func(a *AccountAttributes) { a.SecondaryIdentification = "A1B2C3D4" }).
+		Build()
+	assert.Nil(t, err)
+	assert.Nil(t, validateUUID(acct.AccountData.ID, []int{4}))
+	assert.Equal(t, "accounts", acct.AccountData.Type)
+	assert.Equal(t, orgID, acct.AccountData.OrganisationID)
+	assert.Equal(t, AccountAttributes{
+		Country:                 "GB",
+		BaseCurrency:            "GBP",
+		AccountNumber:           "41426819",
+		BankID:                  "400300",
+		BankIDCode:              "GBDSC",
+		BIC:                     "NWBKGB22",
+		IBAN:                    "GB11NWBK40030041426819",
+		Name:                    []string{"Samantha Holder"},
+		AccountClassification:   String("Personal"),
+		JointAccount:            Bool(false),
+		SecondaryIdentification: "A1B2C3D4",
+	}, acct.AccountData.Attributes)
+
+	// every build generates new ID
+	b := NewAccountBuilder(orgID, "GB")
+	first, _ := b.Build()
+	second, _ := b.Build()
+	assert.NotEqual(t, first.AccountData.ID, second.AccountData.ID)
+
+	// ID versions
+	acct, err = NewAccountBuilder(orgID, "GB").TimeOrderedID().Build()
+	assert.Nil(t, err)
+	assert.Nil(t, validateUUID(acct.AccountData.ID, []int{7}))
+	acct, err = NewAccountBuilder(orgID, "GB").IDFromKey("customer-42").Build()
+	assert.Nil(t, err)
+	id, _ := AccountIDFromKey(orgID, "customer-42")
+	assert.Equal(t, id, acct.AccountData.ID)
+	acct, err = NewAccountBuilder(orgID, "GB").ID("9127e265-9605-4b4b-a0e5-3003ea9cc4dc").Build()
+	assert.Nil(t, err)
+	assert.Equal(t, "9127e265-9605-4b4b-a0e5-3003ea9cc4dc", acct.AccountData.ID)
+
+	// invalid accounts
+	_, err = NewAccountBuilder(orgID, "342").Build()
+	assert.NotNil(t, err)
+	_, err = NewAccountBuilder(orgID, "GB").ID("9127e265").Build()
+	assert.NotNil(t, err)
+	_, err = NewAccountBuilder("invalid", "GB").IDFromKey("customer-42").Build()
+	assert.NotNil(t, err)
+}
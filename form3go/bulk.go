@@ -0,0 +1,214 @@
+package form3go
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultBulkWorkers is number of concurrent requests of bulk operations
+// when BulkOptions.Workers is not set.
+const DefaultBulkWorkers = 8
+
+// BulkOptions are options of BulkCreateAccounts and BulkDeleteAccounts.
+type BulkOptions struct {
+	// Workers is number of concurrent requests. Requests are also subject
+	// to client rate limit, see WithRateLimit.
+	Workers int
+	// CheckpointFile is path of file where IDs of completed accounts are
+	// appended along with operation, e.g. "create <id>". Accounts
+	// recorded in existing file by the same operation are skipped, so bulk
+	// operation interrupted e.g. by crash or cancelled context resumes
+	// where it stopped when run again with the same file.
+	CheckpointFile string
+}
+
+// BulkResult is result of bulk operation on single account.
+type BulkResult struct {
+	// Index and ID of account in input of bulk operation.
+	Index int
+	ID    string
+	// Account is created account, set by BulkCreateAccounts only.
+	Account Account
+	// Skipped is set when account was completed by previous run recorded
+	// in checkpoint file.
+	Skipped bool
+	// Err is nil on success, ErrInvalidAccount on validation failure or
+	// APIError returned by Account API.
+	Err error
+	// CheckpointErr is set when operation succeeded but could not be
+	// recorded in checkpoint file, so it would be repeated by resumed run.
+	CheckpointErr error
+}
+
+// BulkCreateAccounts creates accounts concurrently
+func (c *Client) BulkCreateAccounts(accounts []Account, opts BulkOptions) (<-chan BulkResult, error) {
+	return c.BulkCreateAccountsWithContext(context.Background(), accounts, opts)
+}
+
+// BulkCreateAccountsWithContext creates accounts concurrently by
+// opts.Workers requests at a time. Result of every account is sent on
+// returned channel in order of completion, and the channel is closed
+// when all accounts are processed. Callers must receive all results or
+// cancel ctx, which stops starting new requests and aborts running ones.
+func (c *Client) BulkCreateAccountsWithContext(ctx context.Context, accounts []Account, opts BulkOptions) (<-chan BulkResult, error) {
+	return c.bulk(ctx, "create", len(accounts), opts,
+		func(i int) string { return accounts[i].AccountData.ID },
+		func(ctx context.Context, i int) (Account, error) {
+			return c.CreateAccountWithContext(ctx, accounts[i])
+		})
+}
+
+// BulkDeleteAccounts removes accounts concurrently
+func (c *Client) BulkDeleteAccounts(accounts []Account, opts BulkOptions) (<-chan BulkResult, error) {
+	return c.BulkDeleteAccountsWithContext(context.Background(), accounts, opts)
+}
+
+// BulkDeleteAccountsWithContext removes accounts at their versions
+// concurrently by opts.Workers requests at a time, see
+// BulkCreateAccountsWithContext.
+func (c *Client) BulkDeleteAccountsWithContext(ctx context.Context, accounts []Account, opts BulkOptions) (<-chan BulkResult, error) {
+	return c.bulk(ctx, "delete", len(accounts), opts,
+		func(i int) string { return accounts[i].AccountData.ID },
+		func(ctx context.Context, i int) (Account, error) {
+			data := accounts[i].AccountData
+			return Account{}, c.DeleteAccountWithContext(ctx, data.ID, data.Version)
+		})
+}
+
+// bulk opens checkpoint of operation name and runs op for n items, see
+// runBulk.
+func (c *Client) bulk(ctx context.Context, name string, n int, opts BulkOptions, id func(i int) string, op func(ctx context.Context, i int) (Account, error)) (<-chan BulkResult, error) {
+	cp, err := openCheckpoint(opts.CheckpointFile, name)
+	if err != nil {
+		return nil, err
+	}
+	return runBulk(ctx, cp, n, opts.Workers, id, op), nil
+}
+
+// runBulk runs op for n items identified by id on pool of workers,
+// skipping and recording completed items in cp.
+func runBulk(ctx context.Context, cp *checkpoint, n, workers int, id func(i int) string, op func(ctx context.Context, i int) (Account, error)) <-chan BulkResult {
+	if workers <= 0 {
+		workers = DefaultBulkWorkers
+	}
+
+	results := make(chan BulkResult, workers)
+	send := func(r BulkResult) bool {
+		select {
+		case results <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	items := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				r := BulkResult{Index: i, ID: id(i)}
+				r.Account, r.Err = op(ctx, i)
+				if r.Err == nil {
+					r.CheckpointErr = cp.record(r.ID)
+				}
+				send(r)
+			}
+		}()
+	}
+
+	go func() {
+	feed:
+		for i := 0; i < n; i++ {
+			if cp.completed(id(i)) {
+				if !send(BulkResult{Index: i, ID: id(i), Skipped: true}) {
+					break feed
+				}
+				continue
+			}
+			select {
+			case items <- i:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(items)
+		wg.Wait()
+		cp.close()
+		close(results)
+	}()
+	return results
+}
+
+// checkpoint records IDs of completed items of bulk operation in file,
+// one per line prefixed with operation, e.g. "delete <id>". Nil
+// checkpoint records nothing.
+type checkpoint struct {
+	mu   sync.Mutex
+	f    *os.File
+	op   string
+	done map[string]bool
+}
+
+// openCheckpoint loads items completed by operation op from checkpoint
+// file at path, creating it if it does not exist. Items recorded by other
+// operations are ignored. It returns nil checkpoint if path is empty.
+func openCheckpoint(path, op string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("form3go: opening checkpoint file: %v", err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("form3go: reading checkpoint file: %v", err)
+	}
+	cp := &checkpoint{f: f, op: op, done: map[string]bool{}}
+	for _, line := range strings.Split(string(data), "\n") {
+		if id := strings.TrimPrefix(line, op+" "); id != line && id != "" {
+			cp.done[id] = true
+		}
+	}
+	// terminate line cut short by interruption, so that it is not joined
+	// with the next recorded ID
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := f.WriteString("\n"); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("form3go: writing checkpoint file: %v", err)
+		}
+	}
+	return cp, nil
+}
+
+// completed reports whether item with id was recorded by previous run.
+func (cp *checkpoint) completed(id string) bool {
+	return cp != nil && cp.done[id]
+}
+
+// record appends id of completed item to checkpoint file.
+func (cp *checkpoint) record(id string) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if _, err := cp.f.WriteString(cp.op + " " + id + "\n"); err != nil {
+		return fmt.Errorf("form3go: recording %s in checkpoint file: %v", id, err)
+	}
+	return nil
+}
+
+func (cp *checkpoint) close() {
+	if cp != nil {
+		cp.f.Close()
+	}
+}
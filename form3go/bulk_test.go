@@ -0,0 +1,192 @@
+package form3go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBulkAccounts returns n valid accounts.
+func newBulkAccounts(t *testing.T, n int) []Account {
+	accounts := make([]Account, n)
+	for i := range accounts {
+		assert.Nil(t, json.Unmarshal([]byte(testAccountInfo), &accounts[i]))
+		accounts[i].AccountData.ID = fmt.Sprintf("9127e265-9605-4b4b-a0e5-3003ea9c%04d", i)
+	}
+	return accounts
+}
+
+// collect receives all results of bulk operation by index.
+func collect(results <-chan BulkResult) map[int]BulkResult {
+	byIndex := map[int]BulkResult{}
+	for r := range results {
+		byIndex[r.Index] = r
+	}
+	return byIndex
+}
+
+func TestBulkCreateAccounts(t *testing.T) {
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	created := map[string]int{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "9127e265-9605-4b4b-a0e5-3003ea9c0007") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var acct Account
+		_ = json.Unmarshal(body, &acct)
+		mu.Lock()
+		created[acct.AccountData.ID]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+
+	accounts := newBulkAccounts(t, 20)
+	accounts[3].AccountData.Attributes.Country = "342"
+	results, err := client.BulkCreateAccounts(accounts, BulkOptions{Workers: 4})
+	assert.Nil(t, err)
+	byIndex := collect(results)
+
+	assert.Equal(t, 20, len(byIndex))
+	assert.Equal(t, 18, len(created))
+	assert.True(t, atomic.LoadInt32(&maxInFlight) <= 4)
+	for i, r := range byIndex {
+		assert.Equal(t, accounts[i].AccountData.ID, r.ID)
+		switch i {
+		case 3:
+			assert.True(t, errors.Is(r.Err, ErrInvalidAccount))
+		case 7:
+			assert.True(t, IsValidation(r.Err))
+			assert.True(t, errors.Is(r.Err, ErrCreateAccount))
+		default:
+			assert.Nil(t, r.Err)
+			assert.Equal(t, accounts[i], r.Account)
+		}
+	}
+}
+
+func TestBulkCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "form3go")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint")
+
+	var deleted int32
+	failing := int32(1)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 && strings.HasSuffix(r.URL.Path, "0002") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&deleted, 1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	accounts := newBulkAccounts(t, 5)
+
+	// account 3 was created by other run, and previous run was interrupted
+	// while recording account 1
+	checkpoint := "create " + accounts[3].AccountData.ID + "\n" +
+		"delete " + accounts[0].AccountData.ID + "\n" +
+		"delete " + accounts[1].AccountData.ID[:10]
+	assert.Nil(t, ioutil.WriteFile(path, []byte(checkpoint), 0600))
+	results, err := client.BulkDeleteAccounts(accounts, BulkOptions{Workers: 2, CheckpointFile: path})
+	assert.Nil(t, err)
+	byIndex := collect(results)
+	assert.True(t, byIndex[0].Skipped)
+	assert.Nil(t, byIndex[1].Err)
+	assert.True(t, IsNotFound(byIndex[2].Err))
+	assert.False(t, byIndex[3].Skipped)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&deleted))
+
+	// resumed run deletes only failed account
+	atomic.StoreInt32(&failing, 0)
+	results, err = client.BulkDeleteAccounts(accounts, BulkOptions{CheckpointFile: path})
+	assert.Nil(t, err)
+	byIndex = collect(results)
+	for i, r := range byIndex {
+		assert.Nil(t, r.Err)
+		assert.Nil(t, r.CheckpointErr)
+		assert.Equal(t, i != 2, r.Skipped)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&deleted))
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, strings.Split(checkpoint, "\n"), lines[:3])
+	var recorded []string
+	for _, i := range []int{1, 2, 3, 4} {
+		recorded = append(recorded, "delete "+accounts[i].AccountData.ID)
+	}
+	assert.ElementsMatch(t, recorded, lines[3:])
+
+	_, err = client.BulkDeleteAccounts(accounts, BulkOptions{CheckpointFile: dir})
+	assert.NotNil(t, err)
+}
+
+func TestBulkCheckpointError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "form3go")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	accounts := newBulkAccounts(t, 1)
+
+	// checkpoint file becomes unwritable once operation succeeds
+	cp, err := openCheckpoint(filepath.Join(dir, "checkpoint"), "delete")
+	assert.Nil(t, err)
+	results := runBulk(context.Background(), cp, len(accounts), 1,
+		func(i int) string { return accounts[i].AccountData.ID },
+		func(ctx context.Context, i int) (Account, error) {
+			data := accounts[i].AccountData
+			err := client.DeleteAccountWithContext(ctx, data.ID, data.Version)
+			cp.f.Close()
+			return Account{}, err
+		})
+	r := <-results
+	assert.Nil(t, r.Err)
+	assert.NotNil(t, r.CheckpointErr)
+}
+
+func TestBulkCancel(t *testing.T) {
+	var requests int32
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 3 {
+			cancel()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	results, err := client.BulkDeleteAccountsWithContext(ctx, newBulkAccounts(t, 100), BulkOptions{Workers: 1})
+	assert.Nil(t, err)
+	for range results {
+	}
+	assert.True(t, atomic.LoadInt32(&requests) < 100)
+}
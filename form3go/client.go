@@ -1,210 +1,420 @@
 package form3go
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var (
-	host    = os.Getenv("FORM3_HOST")
-	baseURL = "http://" + host
-
-	acctURL    = "/v1/organisation/accounts"
-	acctReqURL = baseURL + acctURL
+	acctURL = "/v1/organisation/accounts"
 
 	// Errors used by the library
 
-	// ErrInvalidAccount is returned by CreateAccount when account
-	// information is invalid.
+	// ErrInvalidAccount is matched by error returned by CreateAccount and
+	// UpdateAccount when account information is invalid. The error
+	// describes which field failed validation.
 	ErrInvalidAccount = errors.New("form3go: invalid request body")
 
-	// ErrEmptyHost is returned by CreateAccount when FORM3_HOST env
+	// ErrEmptyHost is returned by NewClientFromEnv when FORM3_HOST env
 	// variable is not provided.
 	ErrEmptyHost = errors.New("form3go: FORM3_HOST env variable is required")
 
-	// ErrParameterEmpty is returned by FetchAccount and DeleteAccount
-	// ListAccounts when provided parameters are empty.
+	// ErrEmptyBaseURL is returned by NewClient when no base URL is
+	// configured with WithBaseURL.
+	ErrEmptyBaseURL = errors.New("form3go: base URL is required")
+
+	// ErrInvalidBaseURL is returned by NewClient when provided base URL
+	// is not an absolute http or https URL.
+	ErrInvalidBaseURL = errors.New("form3go: invalid base URL")
+
+	// ErrParameterEmpty is returned by FetchAccount, UpdateAccount,
+	// DeleteAccount and FindAccounts when provided parameters are empty
+	// or invalid.
 	ErrParameterEmpty = errors.New("form3go: invalid parameter")
 
-	// ErrCreateAccount is returned by CreateAccount when creating
-	// account is failed.
+	// ErrCreateAccount is matched by APIError returned by CreateAccount
+	// when creating account is failed.
 	ErrCreateAccount = errors.New("form3go: create account failure")
 
-	// ErrDeleteAccount is returned by DeleteAccount when deleting
-	// account is failed.
+	// ErrDeleteAccount is matched by APIError returned by DeleteAccount
+	// when deleting account is failed.
 	ErrDeleteAccount = errors.New("form3go: delete account failure")
+
+	// ErrFetchAccount is matched by APIError returned by FetchAccount
+	// when fetching account is failed.
+	ErrFetchAccount = errors.New("form3go: fetch account failure")
+
+	// ErrUpdateAccount is matched by APIError returned by UpdateAccount
+	// when updating account is failed.
+	ErrUpdateAccount = errors.New("form3go: update account failure")
+
+	// ErrVersionConflict is matched by ConflictError returned when
+	// account version is stale.
+	ErrVersionConflict = errors.New("form3go: account version conflict")
+
+	// ErrAccountConflict is matched by AccountConflictError returned by
+	// CreateAccount in idempotent mode when account with the same ID
+	// exists with different attributes.
+	ErrAccountConflict = errors.New("form3go: account exists with different attributes")
+
+	// ErrListAccounts is matched by APIError returned by ListAccounts
+	// when listing accounts is failed.
+	ErrListAccounts = errors.New("form3go: list accounts failure")
+
+	// ErrNotFound is matched by APIError of 404 responses.
+	ErrNotFound = errors.New("form3go: resource not found")
+
+	// ErrUnauthorized is matched by APIError of 401 and 403 responses.
+	ErrUnauthorized = errors.New("form3go: request not authorized")
+
+	// ErrServer is matched by APIError of 5xx responses.
+	ErrServer = errors.New("form3go: server failure")
 )
 
-type Client struct {
-	PubKeyID    string
-	PrivKeyPath string
+// defaultUserAgent is sent when WithUserAgent is not provided.
+const defaultUserAgent = "form3go"
 
-	HttpClient http.Client
+// Client is Form3 Account API client. Clients are created with NewClient
+// or NewClientFromEnv and every request is built from the client's own
+// configuration.
+type Client struct {
+	// clockOffset is added to clock after server reported clock skew,
+	// it is accessed atomically and kept first for 64-bit alignment.
+	clockOffset int64
+
+	baseURL   *url.URL
+	userAgent string
+	clock     Clock
+	logger    *log.Logger
+
+	// request signing
+	keyID      string
+	keyPath    string
+	keyPEM     []byte
+	keyReload  time.Duration
+	keyFile    *keyFile
+	keyRing    *KeyRing
+	onRotate   func(KeyRotation)
+	passphrase PassphraseFunc
+	signer     Signer
+	signOpts   signOptions
+
+	retry   RetryPolicy
+	limiter *rateLimiter
+
+	idempotentCreate bool
+	validateOpts     ValidateOptions
+
+	tlsOpts     *tlsOptions
+	httpClient  *http.Client
+	middlewares []Middleware
+	doer        Doer
 }
 
-// CreateAccount creates account.
-func (c *Client) CreateAccount(acct Account) (Account, error) {
-	// validate given account info
-	if err := acct.Validate(); err != nil {
-		return Account{}, ErrInvalidAccount
+// NewClient creates client configured with given options.
+// WithBaseURL is required.
+func NewClient(opts ...Option) (*Client, error) {
+	c := &Client{
+		userAgent:  defaultUserAgent,
+		httpClient: &http.Client{},
 	}
-
-	// create request
-	acctByte, err := json.Marshal(acct)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected JSON marshal failure: %v", err)
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
 	}
-	req, err := http.NewRequest("POST", acctReqURL, bytes.NewBuffer(acctByte))
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+	if c.baseURL == nil {
+		return nil, ErrEmptyBaseURL
 	}
+	if c.keyPEM != nil && c.signer == nil {
+		signer, err := parsePrivateKey(c.keyPEM, c.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		c.signer = signer
+	}
+	if c.keyPath != "" && c.signer == nil {
+		kf, err := newKeyFile(c.keyPath, c.passphrase, c.keyReload)
+		if err != nil {
+			return nil, fmt.Errorf("form3go: loading private key: %v", err)
+		}
+		c.keyFile = kf
+	}
+	if err := c.applyTLS(); err != nil {
+		return nil, err
+	}
+	c.doer = c.chain()
+	return c, nil
+}
 
-	// generate header informations
-	reqInfo := request{
-		data:     string(acctByte),
-		endpoint: acctReqURL,
-		method:   "POST",
-		keyPath:  c.PrivKeyPath,
-		keyID:    c.PubKeyID,
+// NewClientFromEnv creates client from FORM3_HOST, FORM3_KEY_ID and
+// FORM3_PRIV_KEY_PATH env variables. FORM3_HOST may include scheme, e.g.
+// "https://api.form3.tech", http is assumed when it does not. Additional
+// options are applied after env configuration.
+func NewClientFromEnv(opts ...Option) (*Client, error) {
+	host := os.Getenv("FORM3_HOST")
+	if host == "" {
+		return nil, ErrEmptyHost
 	}
-	date := reqInfo.genDateHeader()
-	digest := reqInfo.genDigestHeader()
-	sig, err := reqInfo.genSignature(date, digest)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected generating Signature failure: %v", err)
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
 	}
-	authHeader, err := reqInfo.genAuthHeader(sig)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected generating Authorization header failure: %v", err)
+	envOpts := []Option{
+		WithBaseURL(host),
+		WithKeyID(os.Getenv("FORM3_KEY_ID")),
+		WithPrivateKeyPath(os.Getenv("FORM3_PRIV_KEY_PATH")),
 	}
-	req.Header.Set("Host", os.Getenv("FORM3_HOST"))
-	req.Header.Set("Date", date)
-	req.Header.Set("Authorization", authHeader)
-	req.Header.Set("Digest", digest)
-	req.Header.Set("Content-Type", "application/vnd.api+json")
-	req.Header.Set("Content-Length", strconv.Itoa(len(string(acctByte))))
-
-	// do request
-	resp, err := c.HttpClient.Do(req)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+	return NewClient(append(envOpts, opts...)...)
+}
+
+// ctxError returns ctx error when ctx is done, otherwise err.
+// Returned error matches context.Canceled or context.DeadlineExceeded
+// with errors.Is so callers can distinguish cancellation from deadline.
+func ctxError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case nil:
+		return err
+	case context.DeadlineExceeded:
+		return fmt.Errorf("form3go: request deadline exceeded: %w", ctx.Err())
+	default:
+		return fmt.Errorf("form3go: request canceled: %w", ctx.Err())
 	}
-	defer resp.Body.Close()
+}
+
+// endpoint returns absolute URL of given API path.
+func (c *Client) endpoint(path string) string {
+	return c.baseURL.String() + path
+}
 
-	// check response
-	if resp.StatusCode != 201 {
-		return Account{}, ErrCreateAccount
+// CreateAccount creates account.
+func (c *Client) CreateAccount(acct Account) (Account, error) {
+	return c.CreateAccountWithContext(context.Background(), acct)
+}
+
+// CreateAccountWithContext creates account. Cancelling ctx aborts the
+// request. In idempotent mode, see WithIdempotentCreate, existing account
+// with the same ID and attributes is returned instead of conflict error.
+func (c *Client) CreateAccountWithContext(ctx context.Context, acct Account) (Account, error) {
+	// validate given account info
+	if err := acct.ValidateWithOptions(c.validateOpts); err != nil {
+		return Account{}, fmt.Errorf("%w: %v", ErrInvalidAccount, err)
 	}
+
 	account := Account{}
-	err = json.NewDecoder(resp.Body).Decode(&account)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected response decode failure: %v", err)
+	if !c.idempotentCreate {
+		if err := c.do(ctx, ErrCreateAccount, "POST", acctURL, nil, acct, &account); err != nil {
+			return Account{}, err
+		}
+		return account, nil
+	}
+	// conflict of retried attempt is resolved like the one of repeated
+	// call, so request may be retried
+	if err := c.do(withIdempotent(ctx), ErrCreateAccount, "POST", acctURL, nil, acct, &account); err != nil {
+		if IsConflict(err) {
+			return c.existingAccount(ctx, acct, err)
+		}
+		return Account{}, err
 	}
-
 	return account, nil
 }
 
 // FetchAccount fetches account with ID
 func (c *Client) FetchAccount(id string) (Account, error) {
+	return c.FetchAccountWithContext(context.Background(), id)
+}
+
+// FetchAccountWithContext fetches account with ID. Cancelling ctx aborts
+// the request.
+func (c *Client) FetchAccountWithContext(ctx context.Context, id string) (Account, error) {
 	// check id
 	if id == "" {
 		return Account{}, ErrParameterEmpty
 	}
 
-	// create requet
-	req, err := http.NewRequest("GET", acctReqURL+"/"+id, nil)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+	account := Account{}
+	if err := c.do(ctx, ErrFetchAccount, "GET", acctURL+"/"+url.PathEscape(id), nil, nil, &account); err != nil {
+		return Account{}, err
 	}
+	return account, nil
+}
 
-	// generate headers
-	reqInfo := request{
-		endpoint: acctReqURL,
-		method:   "GET",
-		keyPath:  c.PrivKeyPath,
-		keyID:    c.PubKeyID,
-	}
-	date := reqInfo.genDateHeader()
-	sig, err := reqInfo.genSignature(date, "")
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected generating Signature failure: %v", err)
+// UpdateAccount updates attributes of account with ID which are set in
+// patch. version must be current version of account, ConflictError is
+// returned when it is stale.
+func (c *Client) UpdateAccount(id string, version int, patch AccountPatch) (Account, error) {
+	return c.UpdateAccountWithContext(context.Background(), id, version, patch)
+}
+
+// UpdateAccountWithContext updates attributes of account with ID which
+// are set in patch. Cancelling ctx aborts the request.
+func (c *Client) UpdateAccountWithContext(ctx context.Context, id string, version int, patch AccountPatch) (Account, error) {
+	// check parameters
+	if id == "" || version < 0 || patch.isEmpty() {
+		return Account{}, ErrParameterEmpty
 	}
-	authHeader, err := reqInfo.genAuthHeader(sig)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected generating Authorization header failure: %v", err)
+	// validate given attributes
+	if err := patch.Validate(); err != nil {
+		return Account{}, fmt.Errorf("%w: %v", ErrInvalidAccount, err)
 	}
-	req.Header.Set("Host", os.Getenv("FORM3_HOST"))
-	req.Header.Set("Date", date)
-	req.Header.Set("Authorization", authHeader)
 
-	// do request
-	resp, err := c.HttpClient.Do(req)
-	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
-	}
-	defer resp.Body.Close()
+	body := struct {
+		Data struct {
+			ID         string       `json:"id"`
+			Type       string       `json:"type"`
+			Version    int          `json:"version"`
+			Attributes AccountPatch `json:"attributes"`
+		} `json:"data"`
+	}{}
+	body.Data.ID = id
+	body.Data.Type = "accounts"
+	body.Data.Version = version
+	body.Data.Attributes = patch
 
-	// check response
 	account := Account{}
-	err = json.NewDecoder(resp.Body).Decode(&account)
+	err := c.do(ctx, ErrUpdateAccount, "PATCH", acctURL+"/"+url.PathEscape(id), nil, body, &account)
 	if err != nil {
-		return Account{}, fmt.Errorf("form3go: unexpected response decode failure: %v", err)
+		return Account{}, conflictError(err, id, version)
 	}
-
 	return account, nil
 }
 
 // ListAccounts returns array of accounts
 func (c *Client) ListAccounts(pageNumber, pageSize int) ([]Account, error) {
-	// create request
-	num := strconv.Itoa(pageNumber)
-	size := strconv.Itoa(pageSize)
-	req, err := http.NewRequest("GET", acctReqURL+"?page[number]="+num+"&page[size]="+size, nil)
-	if err != nil {
-		return []Account{}, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+	return c.ListAccountsWithContext(context.Background(), pageNumber, pageSize)
+}
+
+// ListAccountsWithContext returns array of accounts. Cancelling ctx aborts
+// the request.
+func (c *Client) ListAccountsWithContext(ctx context.Context, pageNumber, pageSize int) ([]Account, error) {
+	query := url.Values{}
+	query.Set("page[number]", strconv.Itoa(pageNumber))
+	query.Set("page[size]", strconv.Itoa(pageSize))
+	accounts, _, err := c.listAccounts(ctx, query)
+	return accounts, err
+}
+
+// ListAccountsOptions are filters, sort order and page of accounts listed
+// by FindAccounts. Empty fields are not sent.
+type ListAccountsOptions struct {
+	// Filters matching account attributes
+	BankID        string
+	BankIDCode    string
+	AccountNumber string
+	IBAN          string
+	Country       string
+	CustomerID    string
+
+	// Sort is attribute accounts are sorted by, prefixed with "-" for
+	// descending order, e.g. "-created_on".
+	Sort string
+
+	// PageNumber and PageSize select page of accounts. Server default page
+	// size is used when PageSize is 0.
+	PageNumber int
+	PageSize   int
+}
+
+// query returns URL query of o.
+func (o ListAccountsOptions) query() url.Values {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"filter[bank_id]":        o.BankID,
+		"filter[bank_id_code]":   o.BankIDCode,
+		"filter[account_number]": o.AccountNumber,
+		"filter[iban]":           o.IBAN,
+		"filter[country]":        o.Country,
+		"filter[customer_id]":    o.CustomerID,
+		"sort":                   o.Sort,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	query.Set("page[number]", strconv.Itoa(o.PageNumber))
+	if o.PageSize > 0 {
+		query.Set("page[size]", strconv.Itoa(o.PageSize))
 	}
+	return query
+}
+
+// FindAccounts returns page of accounts matching opts
+func (c *Client) FindAccounts(opts ListAccountsOptions) ([]Account, error) {
+	return c.FindAccountsWithContext(context.Background(), opts)
+}
 
-	// generate headers
-	reqInfo := request{
-		endpoint: acctReqURL,
-		method:   "GET",
-		keyPath:  c.PrivKeyPath,
-		keyID:    c.PubKeyID,
+// FindAccountsWithContext returns page of accounts matching opts.
+// Cancelling ctx aborts the request.
+func (c *Client) FindAccountsWithContext(ctx context.Context, opts ListAccountsOptions) ([]Account, error) {
+	if opts.PageNumber < 0 || opts.PageSize < 0 {
+		return []Account{}, ErrParameterEmpty
 	}
-	date := reqInfo.genDateHeader()
-	sig, err := reqInfo.genSignature(date, "")
-	if err != nil {
-		return []Account{}, fmt.Errorf("form3go: unexpected generating Signature failure: %v", err)
+	accounts, _, err := c.listAccounts(ctx, opts.query())
+	return accounts, err
+}
+
+// FindAccountByIBAN returns account with IBAN
+func (c *Client) FindAccountByIBAN(iban string) (Account, error) {
+	return c.FindAccountByIBANWithContext(context.Background(), iban)
+}
+
+// FindAccountByIBANWithContext returns account with IBAN. Error matching
+// ErrNotFound is returned if there is none. Cancelling ctx aborts the
+// request.
+func (c *Client) FindAccountByIBANWithContext(ctx context.Context, iban string) (Account, error) {
+	if iban == "" {
+		return Account{}, ErrParameterEmpty
 	}
-	authHeader, err := reqInfo.genAuthHeader(sig)
-	if err != nil {
-		return []Account{}, fmt.Errorf("form3go: unexpected generating Authorization header failure: %v", err)
+	return c.findAccount(ctx, ListAccountsOptions{IBAN: iban}, "IBAN "+iban)
+}
+
+// FindAccountByNumber returns account with account number and bank ID
+func (c *Client) FindAccountByNumber(accountNumber, bankID string) (Account, error) {
+	return c.FindAccountByNumberWithContext(context.Background(), accountNumber, bankID)
+}
+
+// FindAccountByNumberWithContext returns account with account number
+// and bank ID, since account numbers are unique only within a bank.
+// Error matching ErrNotFound is returned if there is none. Cancelling
+// ctx aborts the request.
+func (c *Client) FindAccountByNumberWithContext(ctx context.Context, accountNumber, bankID string) (Account, error) {
+	if accountNumber == "" || bankID == "" {
+		return Account{}, ErrParameterEmpty
 	}
-	req.Header.Set("Host", os.Getenv("FORM3_HOST"))
-	req.Header.Set("Date", date)
-	req.Header.Set("Authorization", authHeader)
+	return c.findAccount(ctx, ListAccountsOptions{AccountNumber: accountNumber, BankID: bankID}, "account number "+accountNumber)
+}
 
-	// do request
-	resp, err := c.HttpClient.Do(req)
+// findAccount returns the first account matching opts, described by desc
+// in not found error.
+func (c *Client) findAccount(ctx context.Context, opts ListAccountsOptions, desc string) (Account, error) {
+	opts.PageSize = 1
+	accounts, _, err := c.listAccounts(ctx, opts.query())
 	if err != nil {
-		return []Account{}, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+		return Account{}, err
 	}
-	defer resp.Body.Close()
+	if len(accounts) == 0 {
+		return Account{}, fmt.Errorf("%w: no account with %s", ErrNotFound, desc)
+	}
+	return accounts[0], nil
+}
 
-	// check response
+// listAccounts returns page of accounts of query with its links.
+func (c *Client) listAccounts(ctx context.Context, query url.Values) ([]Account, Links, error) {
 	accts := struct {
 		Accounts []Data `json:"data"`
+		Links    Links  `json:"links"`
 	}{
 		Accounts: []Data{},
 	}
-	err = json.NewDecoder(resp.Body).Decode(&accts)
-	if err != nil {
-		return []Account{}, fmt.Errorf("form3go: unexpected response decode failure: %v", err)
+	if err := c.do(ctx, ErrListAccounts, "GET", acctURL, query, nil, &accts); err != nil {
+		return []Account{}, Links{}, err
 	}
 
 	// adjust response
@@ -215,53 +425,52 @@ func (c *Client) ListAccounts(pageNumber, pageSize int) ([]Account, error) {
 		}
 		accounts = append(accounts, account)
 	}
-	return accounts, nil
+	return accounts, accts.Links, nil
 }
 
-// DeleteAccount removes account with ID
-func (c *Client) DeleteAccount(id, version string) error {
+// DeleteAccount removes account with ID and version
+func (c *Client) DeleteAccount(id string, version int) error {
+	return c.DeleteAccountWithContext(context.Background(), id, version)
+}
+
+// DeleteAccountWithContext removes account with ID and version. If
+// account was modified since version, ConflictError is returned.
+// Cancelling ctx aborts the request.
+func (c *Client) DeleteAccountWithContext(ctx context.Context, id string, version int) error {
 	// check parameters
-	if id == "" || version == "" {
+	if id == "" || version < 0 {
 		return ErrParameterEmpty
 	}
 
-	// create request
-	req, err := http.NewRequest("DELETE", acctReqURL+"/"+id+"?version="+version, nil)
-	if err != nil {
-		return fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
-	}
+	query := url.Values{}
+	query.Set("version", strconv.Itoa(version))
+	err := c.do(ctx, ErrDeleteAccount, "DELETE", acctURL+"/"+url.PathEscape(id), query, nil, nil)
+	return conflictError(err, id, version)
+}
 
-	// create request
-	reqInfo := request{
-		endpoint: acctReqURL,
-		method:   "DELETE",
-		keyPath:  c.PrivKeyPath,
-		keyID:    c.PubKeyID,
-	}
-	date := reqInfo.genDateHeader()
-	sig, err := reqInfo.genSignature(date, "")
-	if err != nil {
-		return fmt.Errorf("form3go: unexpected generating Signature failure: %v", err)
-	}
-	authHeader, err := reqInfo.genAuthHeader(sig)
-	if err != nil {
-		return fmt.Errorf("form3go: unexpected generating Authorization header failure: %v", err)
-	}
-	req.Header.Set("Host", os.Getenv("FORM3_HOST"))
-	req.Header.Set("Date", date)
-	req.Header.Set("Authorization", authHeader)
+// deleteLatestAttempts is number of times DeleteAccountLatest fetches
+// and deletes account before giving up on version conflicts.
+const deleteLatestAttempts = 3
 
-	// do request
-	resp, err := c.HttpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
-	}
-	defer resp.Body.Close()
+// DeleteAccountLatest removes account with ID at its current version
+func (c *Client) DeleteAccountLatest(id string) error {
+	return c.DeleteAccountLatestWithContext(context.Background(), id)
+}
 
-	// check response
-	if resp.StatusCode != 204 {
-		return ErrDeleteAccount
+// DeleteAccountLatestWithContext fetches account with ID and removes it
+// at fetched version. If account is modified in between, it is fetched
+// and deleted again. Cancelling ctx aborts the requests.
+func (c *Client) DeleteAccountLatestWithContext(ctx context.Context, id string) error {
+	var err error
+	for i := 0; i < deleteLatestAttempts; i++ {
+		var account Account
+		if account, err = c.FetchAccountWithContext(ctx, id); err != nil {
+			return err
+		}
+		err = c.DeleteAccountWithContext(ctx, id, account.AccountData.Version)
+		if !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
 	}
-
-	return nil
+	return err
 }
@@ -1,44 +1,86 @@
 package form3go
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
-	"os"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	client = Client{
-		PubKeyID:    os.Getenv("FORM3_KEY_ID"),
-		PrivKeyPath: os.Getenv("FORM3_PRIV_KEY_PATH"),
-		HttpClient:  http.Client{},
+// newEnvClient returns client configured from FORM3_* env variables.
+func newEnvClient(t *testing.T) *Client {
+	client, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatalf("NewClientFromEnv: %v", err)
 	}
-)
+	return client
+}
+
+// newTestClient returns client doing requests against test server
+// serving handler. opts are applied after test configuration.
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(append([]Option{
+		WithBaseURL(srv.URL),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithPrivateKeyPath("../test_private_key.pem"),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+// loadTestKey loads test private key fixture, failing the test if it
+// cannot be loaded.
+func loadTestKey(t *testing.T) *rsaPrivateKey {
+	signer, err := loadPrivateKey("../test_private_key.pem", nil)
+	if err != nil {
+		t.Fatalf("loadPrivateKey: %v", err)
+	}
+	key, ok := signer.(*rsaPrivateKey)
+	if !ok {
+		t.Fatalf("test key is %T, not RSA", signer)
+	}
+	return key
+}
 
 func TestCreateAccount(t *testing.T) {
+	client := newEnvClient(t)
 	account := &Account{}
 	_ = json.Unmarshal([]byte(testAccountInfo), account)
 
 	createdAccount, _ := client.CreateAccount(*account)
 	assert.NotNil(t, createdAccount)
-	assert.Equal(t, *account, createdAccount)
+	assert.Equal(t, *account, withoutServerFields(createdAccount)[0])
 }
 
 func TestFetchAccount(t *testing.T) {
+	client := newEnvClient(t)
 	account := &Account{}
 	_ = json.Unmarshal([]byte(testAccountInfo), account)
 
 	fetchedAccount, _ := client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
 	assert.NotNil(t, fetchedAccount)
-	assert.Equal(t, *account, fetchedAccount)
+	assert.Equal(t, *account, withoutServerFields(fetchedAccount)[0])
 
 	_, err := client.FetchAccount("")
 	assert.Equal(t, "form3go: invalid parameter", err.Error())
 }
 
 func TestListAccounts(t *testing.T) {
+	client := newEnvClient(t)
 	account := &Account{}
 	_ = json.Unmarshal([]byte(testAccountInfo), account)
 	accounts := []Account{}
@@ -46,7 +88,7 @@ func TestListAccounts(t *testing.T) {
 
 	listedAccounts, _ := client.ListAccounts(0, 1)
 	assert.NotNil(t, listedAccounts)
-	assert.Equal(t, accounts, listedAccounts)
+	assert.Equal(t, accounts, withoutServerFields(listedAccounts...))
 
 	listedAccounts, _ = client.ListAccounts(1, 1)
 	assert.NotNil(t, listedAccounts)
@@ -58,15 +100,290 @@ func TestListAccounts(t *testing.T) {
 }
 
 func TestDeleteAccount(t *testing.T) {
+	client := newEnvClient(t)
 	account := &Account{}
 	_ = json.Unmarshal([]byte(testAccountInfo), account)
 
-	err := client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc", "0")
+	err := client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc", 0)
 	assert.Nil(t, err)
 
-	err = client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc", "2")
+	err = client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc", 2)
 	assert.Nil(t, err)
 
-	err = client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4d", "0")
+	err = client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4d", 0)
 	assert.NotNil(t, err)
 }
+
+func TestClientContext(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	// Canceled before request is signed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.FetchAccountWithContext(ctx, "9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "form3go: request canceled: context canceled", err.Error())
+
+	// Canceled while request is in flight
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = client.ListAccountsWithContext(ctx, 0, 1)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// Deadline exceeded while request is in flight
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = client.DeleteAccountWithContext(ctx, "9127e265-9605-4b4b-a0e5-3003ea9cc4dc", 0)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, context.Canceled))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+	_, err = client.CreateAccountWithContext(ctx, *account)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestClientResponseStatus(t *testing.T) {
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+	id := account.AccountData.ID
+
+	calls := []struct {
+		name  string
+		opErr error
+		call  func(c *Client) error
+	}{
+		{"CreateAccount", ErrCreateAccount, func(c *Client) error { _, err := c.CreateAccount(*account); return err }},
+		{"FetchAccount", ErrFetchAccount, func(c *Client) error { _, err := c.FetchAccount(id); return err }},
+		{"ListAccounts", ErrListAccounts, func(c *Client) error { _, err := c.ListAccounts(0, 1); return err }},
+		{"UpdateAccount", ErrUpdateAccount, func(c *Client) error {
+			_, err := c.UpdateAccount(id, 0, AccountPatch{Country: String("GB")})
+			return err
+		}},
+		{"DeleteAccount", ErrDeleteAccount, func(c *Client) error { return c.DeleteAccount(id, 0) }},
+	}
+	statuses := []struct {
+		status int
+		class  error
+		check  func(error) bool
+	}{
+		{http.StatusNotFound, ErrNotFound, IsNotFound},
+		{http.StatusUnauthorized, ErrUnauthorized, IsUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized, IsUnauthorized},
+		{http.StatusInternalServerError, ErrServer, IsServerError},
+		{http.StatusServiceUnavailable, ErrServer, IsServerError},
+		{http.StatusBadRequest, nil, IsValidation},
+	}
+
+	for _, call := range calls {
+		for _, st := range statuses {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(st.status)
+				_, _ = w.Write([]byte(`{"error_message":"failure"}`))
+			})
+			err := call.call(client)
+
+			var apiErr *APIError
+			assert.True(t, errors.As(err, &apiErr), "%s %d", call.name, st.status)
+			assert.Equal(t, st.status, apiErr.StatusCode, "%s %d", call.name, st.status)
+			assert.Equal(t, "failure", apiErr.Message, "%s %d", call.name, st.status)
+			assert.True(t, errors.Is(err, call.opErr), "%s %d", call.name, st.status)
+			assert.True(t, st.check(err), "%s %d", call.name, st.status)
+			if st.class != nil {
+				assert.True(t, errors.Is(err, st.class), "%s %d", call.name, st.status)
+			}
+		}
+	}
+}
+
+func TestClientResponseSuccess(t *testing.T) {
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+	id := account.AccountData.ID
+	list, _ := json.Marshal(map[string][]Data{"data": {account.AccountData}})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"CreateAccount", "POST", acctURL, http.StatusCreated, testAccountInfo},
+		{"FetchAccount", "GET", acctURL + "/" + id, http.StatusOK, testAccountInfo},
+		{"ListAccounts", "GET", acctURL, http.StatusOK, string(list)},
+		{"UpdateAccount", "PATCH", acctURL + "/" + id, http.StatusOK, testAccountInfo},
+		{"DeleteAccount", "DELETE", acctURL + "/" + id, http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, tt.method, r.Method, tt.name)
+			assert.Equal(t, tt.path, r.URL.Path, tt.name)
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(tt.body))
+		})
+
+		switch tt.name {
+		case "CreateAccount":
+			acct, err := client.CreateAccount(*account)
+			assert.Nil(t, err)
+			assert.Equal(t, *account, acct)
+		case "FetchAccount":
+			acct, err := client.FetchAccount(id)
+			assert.Nil(t, err)
+			assert.Equal(t, *account, acct)
+		case "ListAccounts":
+			accts, err := client.ListAccounts(0, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, []Account{*account}, accts)
+		case "UpdateAccount":
+			acct, err := client.UpdateAccount(id, 0, AccountPatch{Country: String("GB")})
+			assert.Nil(t, err)
+			assert.Equal(t, *account, acct)
+		case "DeleteAccount":
+			assert.Nil(t, client.DeleteAccount(id, 0))
+		}
+	}
+}
+
+func TestUpdateAccount(t *testing.T) {
+	id := "9127e265-9605-4b4b-a0e5-3003ea9cc4dc"
+	var body string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		if strings.Contains(body, `"version":0`) {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error_message":"invalid version"}`))
+			return
+		}
+		_, _ = w.Write([]byte(testAccountInfo))
+	})
+
+	// only set attributes are sent
+	_, err := client.UpdateAccount(id, 1, AccountPatch{BankAccountName: String("Sam Holder"), JointAccount: Bool(false)})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"data":{"id":"`+id+`","type":"accounts","version":1,"attributes":{"bank_account_name":"Sam Holder","joint_account":false}}}`, body)
+
+	// stale version
+	_, err = client.UpdateAccount(id, 0, AccountPatch{BankAccountName: String("Sam Holder")})
+	var conflict *ConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, id, conflict.ID)
+	assert.Equal(t, 0, conflict.Version)
+	assert.Equal(t, "invalid version", conflict.Err.Message)
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	assert.True(t, errors.Is(err, ErrUpdateAccount))
+	assert.True(t, IsConflict(err))
+
+	// invalid parameters
+	body = ""
+	_, err = client.UpdateAccount(id, 1, AccountPatch{})
+	assert.Equal(t, ErrParameterEmpty, err)
+	_, err = client.UpdateAccount("", 1, AccountPatch{Country: String("GB")})
+	assert.Equal(t, ErrParameterEmpty, err)
+	_, err = client.UpdateAccount(id, 1, AccountPatch{Country: String("342")})
+	assert.True(t, errors.Is(err, ErrInvalidAccount))
+	assert.Contains(t, err.Error(), "'Country'")
+	assert.Empty(t, body)
+}
+
+func TestDeleteAccountLatest(t *testing.T) {
+	id := "9127e265-9605-4b4b-a0e5-3003ea9cc4dc"
+	var version, deletes int32
+	latest := int32(2)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"data":{"id":"%s","version":%d}}`, id, atomic.LoadInt32(&version))))
+			return
+		}
+		atomic.AddInt32(&deletes, 1)
+		// account is modified after each fetch until latest version
+		if v := atomic.LoadInt32(&version); v < atomic.LoadInt32(&latest) {
+			atomic.StoreInt32(&version, v+1)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	assert.Nil(t, client.DeleteAccountLatest(id))
+	assert.Equal(t, int32(3), deletes)
+
+	// conflicts are retried a limited number of times
+	atomic.StoreInt32(&latest, 10)
+	err := client.DeleteAccountLatest(id)
+	assert.True(t, errors.Is(err, ErrVersionConflict))
+	assert.True(t, errors.Is(err, ErrDeleteAccount))
+
+	// invalid parameters
+	assert.Equal(t, ErrParameterEmpty, client.DeleteAccount(id, -1))
+	assert.Equal(t, ErrParameterEmpty, client.DeleteAccountLatest(""))
+}
+
+func TestFindAccounts(t *testing.T) {
+	var query url.Values
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		if query.Get("filter[iban]") == "GB00UNKNOWN" {
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"9127e265-9605-4b4b-a0e5-3003ea9cc4dc"}]}`))
+	})
+
+	accounts, err := client.FindAccounts(ListAccountsOptions{
+		BankID:     "400300",
+		BankIDCode: "GBDSC",
+		Country:    "GB",
+		CustomerID: "a&b=c",
+		Sort:       "-created_on",
+		PageNumber: 2,
+		PageSize:   10,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(accounts))
+	assert.Equal(t, url.Values{
+		"filter[bank_id]":      {"400300"},
+		"filter[bank_id_code]": {"GBDSC"},
+		"filter[country]":      {"GB"},
+		"filter[customer_id]":  {"a&b=c"},
+		"sort":                 {"-created_on"},
+		"page[number]":         {"2"},
+		"page[size]":           {"10"},
+	}, query)
+
+	// empty options
+	_, err = client.FindAccounts(ListAccountsOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, url.Values{"page[number]": {"0"}}, query)
+	_, err = client.FindAccounts(ListAccountsOptions{PageSize: -1})
+	assert.Equal(t, ErrParameterEmpty, err)
+
+	// single account
+	acct, err := client.FindAccountByIBAN("GB11NWBK40030041426819")
+	assert.Nil(t, err)
+	assert.Equal(t, "9127e265-9605-4b4b-a0e5-3003ea9cc4dc", acct.AccountData.ID)
+	assert.Equal(t, url.Values{"filter[iban]": {"GB11NWBK40030041426819"}, "page[number]": {"0"}, "page[size]": {"1"}}, query)
+
+	_, err = client.FindAccountByNumber("41426819", "400300")
+	assert.Nil(t, err)
+	assert.Equal(t, "41426819", query.Get("filter[account_number]"))
+	assert.Equal(t, "400300", query.Get("filter[bank_id]"))
+
+	_, err = client.FindAccountByIBAN("GB00UNKNOWN")
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, "form3go: resource not found: no account with IBAN GB00UNKNOWN", err.Error())
+
+	_, err = client.FindAccountByIBAN("")
+	assert.Equal(t, ErrParameterEmpty, err)
+	_, err = client.FindAccountByNumber("41426819", "")
+	assert.Equal(t, ErrParameterEmpty, err)
+}
@@ -0,0 +1,73 @@
+package form3go
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Clock provides current time of client, e.g. for Date header and key
+// ring activation.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts function to Clock.
+type ClockFunc func() time.Time
+
+// Now calls f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// WithClock sets clock of client. System clock is used by default.
+func WithClock(clock Clock) Option {
+	return func(c *Client) error {
+		if clock != nil {
+			c.clock = clock
+		}
+		return nil
+	}
+}
+
+// ClockOffset returns offset added to client clock after server reported
+// clock skew.
+func (c *Client) ClockOffset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.clockOffset))
+}
+
+// now returns current time of client clock adjusted by clock offset.
+func (c *Client) now() time.Time {
+	return c.clockNow().Add(c.ClockOffset())
+}
+
+// clockNow returns current time of client clock without clock offset.
+func (c *Client) clockNow() time.Time {
+	if c.clock != nil {
+		return c.clock.Now()
+	}
+	return time.Now()
+}
+
+// adjustClock sets clock offset when Date header of resp differs from
+// time request was signed at with offset by clockSkewThreshold or more.
+// The offset is replaced only if it is still the one request was signed
+// with, so concurrent requests rejected for the same skew correct it
+// once. It reports whether the offset changed since request was signed.
+func (c *Client) adjustClock(resp *http.Response, signedAt time.Time, offset time.Duration) bool {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return false
+	}
+	skew := date.Sub(signedAt)
+	if skew > -clockSkewThreshold && skew < clockSkewThreshold {
+		return false
+	}
+	adjusted := offset + skew
+	if !atomic.CompareAndSwapInt64(&c.clockOffset, int64(offset), int64(adjusted)) {
+		// corrected by concurrent request
+		return true
+	}
+	c.logf("clock skew of %v reported by server, clock offset is %v", skew, adjusted)
+	return true
+}
@@ -0,0 +1,112 @@
+package form3go
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenDateHeader(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+	date := time.Date(2019, 5, 20, 10, 30, 0, 0, est)
+	assert.Equal(t, "Mon, 20 May 2019 15:30:00 GMT", genDateHeader(date))
+}
+
+func TestClientClock(t *testing.T) {
+	date := time.Date(2019, 5, 20, 10, 30, 0, 0, time.UTC)
+	var received string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Date")
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}, WithClock(ClockFunc(func() time.Time { return date })))
+
+	_, err := client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, "Mon, 20 May 2019 10:30:00 GMT", received)
+	assert.Equal(t, time.Duration(0), client.ClockOffset())
+}
+
+func TestClientClockSkew(t *testing.T) {
+	var requests int32
+	v := newTestVerifier(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		})).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	// client clock is an hour behind
+	var logs bytes.Buffer
+	client, err := NewClient(
+		WithBaseURL(srv.URL),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithPrivateKeyPath("../test_private_key.pem"),
+		WithClock(ClockFunc(func() time.Time { return time.Now().Add(-time.Hour) })),
+		WithLogger(log.New(&logs, "", 0)),
+	)
+	assert.Nil(t, err)
+
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.InDelta(t, float64(time.Hour), float64(client.ClockOffset()), float64(5*time.Second))
+	assert.Contains(t, logs.String(), "form3go: clock skew of")
+
+	// offset is kept for following requests
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	// 401 responses without skew are not retried
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.True(t, IsUnauthorized(err))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+	assert.Equal(t, time.Duration(0), client.ClockOffset())
+}
+
+func TestClientClockSkewConcurrent(t *testing.T) {
+	const concurrent = 4
+	var rejected int32
+	arrived := make(chan struct{})
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		server := time.Now().Add(2 * time.Minute)
+		w.Header().Set("Date", server.UTC().Format(http.TimeFormat))
+		date, _ := http.ParseTime(r.Header.Get("Date"))
+		if d := server.Sub(date); d > clockSkewThreshold || d < -clockSkewThreshold {
+			// hold rejections until all requests are rejected
+			if atomic.AddInt32(&rejected, 1) == concurrent {
+				close(arrived)
+			}
+			<-arrived
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(concurrent), atomic.LoadInt32(&rejected))
+	assert.InDelta(t, float64(2*time.Minute), float64(client.ClockOffset()), float64(5*time.Second))
+}
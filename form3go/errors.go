@@ -0,0 +1,216 @@
+package form3go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize limits how much of error response body is kept.
+const maxErrorBodySize = 1 << 20
+
+// APIError is returned when Account API responds with non-2xx status.
+// It matches sentinel error of failed operation with errors.Is, e.g.
+// errors.Is(err, ErrCreateAccount), as well as status class sentinels
+// ErrNotFound, ErrUnauthorized and ErrServer.
+type APIError struct {
+	// StatusCode is HTTP status code of response.
+	StatusCode int
+	// ErrorCode is Form3 error_code of response body.
+	ErrorCode string
+	// Message is Form3 error_message of response body.
+	Message string
+	// RequestID is X-Request-Id header of response.
+	RequestID string
+	// Body is raw response body.
+	Body []byte
+
+	// Method and URL of failed request.
+	Method string
+	URL    string
+
+	err error
+}
+
+// Error implements error interface.
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("form3go: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.ErrorCode != "" {
+		msg += " (error_code " + e.ErrorCode + ")"
+	}
+	return msg
+}
+
+// Unwrap returns sentinel error of failed operation.
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is status class sentinel matching e.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// ConflictError is returned when request is rejected with 409 Conflict
+// response because account version is stale. Callers should fetch the
+// account and retry with its current version. It matches
+// ErrVersionConflict with errors.Is and unwraps to APIError.
+type ConflictError struct {
+	// ID and Version of account given to the request.
+	ID      string
+	Version int
+	// Err is APIError of 409 response.
+	Err *APIError
+}
+
+// Error implements error interface.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("form3go: account %s version %d is stale: %v", e.ID, e.Version, e.Err)
+}
+
+// Unwrap returns APIError of 409 response.
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrVersionConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// AccountConflictError is returned by CreateAccount in idempotent mode
+// when account with the same ID exists with different attributes. It
+// matches ErrAccountConflict with errors.Is and unwraps to APIError of
+// 409 response.
+type AccountConflictError struct {
+	// ID of account given to CreateAccount.
+	ID string
+	// Diff lists requested fields whose values differ from existing
+	// account.
+	Diff []FieldDiff
+	// Err is APIError of 409 response.
+	Err *APIError
+}
+
+// FieldDiff is field of account with differing values.
+type FieldDiff struct {
+	// Field is JSON path of field, e.g. "attributes.bank_id".
+	Field string
+	// Requested and Existing are JSON values of field, Existing is nil
+	// if existing account does not have the field.
+	Requested interface{}
+	Existing  interface{}
+}
+
+// Error implements error interface.
+func (e *AccountConflictError) Error() string {
+	fields := make([]string, len(e.Diff))
+	for i, d := range e.Diff {
+		fields[i] = fmt.Sprintf("%s (requested %v, existing %v)", d.Field, d.Requested, d.Existing)
+	}
+	return fmt.Sprintf("form3go: account %s exists with different attributes: %s", e.ID, strings.Join(fields, ", "))
+}
+
+// Unwrap returns APIError of 409 response.
+func (e *AccountConflictError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrAccountConflict.
+func (e *AccountConflictError) Is(target error) bool {
+	return target == ErrAccountConflict
+}
+
+// conflictError returns ConflictError of account with id and version if
+// err is caused by 409 response, otherwise err.
+func conflictError(err error, id string, version int) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return &ConflictError{ID: id, Version: version, Err: apiErr}
+	}
+	return err
+}
+
+// newAPIError creates APIError from response. err is sentinel error of
+// failed operation, it may be nil.
+func newAPIError(resp *http.Response, err error) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+		err:        err,
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	apiErr.Body = body
+	errBody := struct {
+		ErrorMessage string `json:"error_message"`
+		ErrorCode    string `json:"error_code"`
+	}{}
+	if json.Unmarshal(body, &errBody) == nil {
+		apiErr.Message = errBody.ErrorMessage
+		apiErr.ErrorCode = errBody.ErrorCode
+	}
+	return apiErr
+}
+
+// statusCode returns HTTP status code of APIError in err chain,
+// or 0 if there is none.
+func statusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is caused by 404 Not Found response.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsUnauthorized reports whether err is caused by 401 Unauthorized or
+// 403 Forbidden response.
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+// IsServerError reports whether err is caused by 5xx response.
+func IsServerError(err error) bool {
+	return errors.Is(err, ErrServer)
+}
+
+// IsConflict reports whether err is caused by 409 Conflict response,
+// e.g. duplicate account ID or stale version.
+func IsConflict(err error) bool {
+	return statusCode(err) == http.StatusConflict
+}
+
+// IsRateLimited reports whether err is caused by 429 Too Many Requests
+// response.
+func IsRateLimited(err error) bool {
+	return statusCode(err) == http.StatusTooManyRequests
+}
+
+// IsValidation reports whether err is caused by invalid request, either
+// rejected by client side validation or by 400 Bad Request response.
+func IsValidation(err error) bool {
+	return errors.Is(err, ErrInvalidAccount) || statusCode(err) == http.StatusBadRequest
+}
@@ -0,0 +1,67 @@
+package form3go
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "5d2b6e5f-2f8f-4d4b-8f3a-1c7e0e2f9a10")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error_message":"Account cannot be created as it violates a duplicate constraint","error_code":"a9cda4a2-3a7f-4c38-8a49-6ee3e1b3e0f7"}`))
+	})
+
+	_, err := client.CreateAccount(*account)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	assert.Equal(t, "a9cda4a2-3a7f-4c38-8a49-6ee3e1b3e0f7", apiErr.ErrorCode)
+	assert.Equal(t, "Account cannot be created as it violates a duplicate constraint", apiErr.Message)
+	assert.Equal(t, "5d2b6e5f-2f8f-4d4b-8f3a-1c7e0e2f9a10", apiErr.RequestID)
+	assert.Equal(t, "POST", apiErr.Method)
+	assert.Equal(t, client.endpoint(acctURL), apiErr.URL)
+	assert.Contains(t, string(apiErr.Body), "duplicate constraint")
+	assert.Equal(t, "form3go: POST "+client.endpoint(acctURL)+": 409 Conflict: Account cannot be created as it violates a duplicate constraint (error_code a9cda4a2-3a7f-4c38-8a49-6ee3e1b3e0f7)", err.Error())
+
+	assert.True(t, errors.Is(err, ErrCreateAccount))
+	assert.False(t, errors.Is(err, ErrDeleteAccount))
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsNotFound(err))
+
+	err = client.DeleteAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc", 0)
+	assert.True(t, errors.Is(err, ErrDeleteAccount))
+	assert.True(t, IsConflict(err))
+}
+
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		err         error
+		notFound    bool
+		conflict    bool
+		rateLimited bool
+		validation  bool
+	}{
+		{err: &APIError{StatusCode: http.StatusNotFound}, notFound: true},
+		{err: &APIError{StatusCode: http.StatusConflict}, conflict: true},
+		{err: &APIError{StatusCode: http.StatusTooManyRequests}, rateLimited: true},
+		{err: &APIError{StatusCode: http.StatusBadRequest}, validation: true},
+		{err: ErrInvalidAccount, validation: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError}},
+		{err: errors.New("form3go: unexpected HTTP request failure")},
+		{err: nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.notFound, IsNotFound(tt.err))
+		assert.Equal(t, tt.conflict, IsConflict(tt.err))
+		assert.Equal(t, tt.rateLimited, IsRateLimited(tt.err))
+		assert.Equal(t, tt.validation, IsValidation(tt.err))
+	}
+}
@@ -1,136 +1,305 @@
 package form3go
 
 import (
-	"crypto"
-	"crypto/rand"
-	"crypto/rsa"
 	"crypto/sha256"
-	"crypto/x509"
+	"crypto/sha512"
 	"encoding/base64"
-	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"os"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
-// request represents request info to endpoints
-type request struct {
-	data     string
-	endpoint string
-	method   string
-	keyPath  string
-	keyID    string
-}
+// clockSkewThreshold is difference between Date header of 401 response
+// and time request was signed at which is treated as clock skew.
+const clockSkewThreshold = 30 * time.Second
 
-// generate Date Header
-func (r *request) genDateHeader() string {
-	return time.Now().Format(time.RFC1123)
+// signRequests is middleware adding Date, Digest and Authorization
+// headers to requests. Requests already carrying Authorization header
+// are passed unchanged so custom auth middleware can replace signing.
+func (c *Client) signRequests(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Authorization") != "" {
+			return next.Do(req)
+		}
+		if err := ctxError(req.Context(), nil); err != nil {
+			return nil, err
+		}
+		if c.keyRing != nil {
+			return c.signWithKeyRing(next, req)
+		}
+		if c.keyID == "" {
+			return nil, errors.New("form3go: unexpected generating Signature failure: empty key ID")
+		}
+		signer := c.signer
+		if signer == nil && c.keyFile != nil {
+			signer = c.keyFile.current()
+		}
+		if signer == nil {
+			return nil, errors.New("form3go: unexpected generating Signature failure: empty private key")
+		}
+		return c.doSigned(next, req, c.keyID, signer)
+	})
 }
 
-// generate Digest Header
-func (r *request) genDigestHeader() string {
-	hash := sha256.Sum256([]byte(r.data))
-	return "SHA-256=" + string(base64.StdEncoding.EncodeToString(hash[:]))
+// signWithKeyRing signs req with current key of key ring. Request
+// rejected with 401 response is signed again with previous key and
+// retried once.
+func (c *Client) signWithKeyRing(next Doer, req *http.Request) (*http.Response, error) {
+	now := c.now()
+	key, rotation, ok := c.keyRing.next(now)
+	if !ok {
+		return nil, errors.New("form3go: unexpected generating Signature failure: no active key")
+	}
+	c.rotated(rotation)
+
+	resp, err := c.doSigned(next, req, key.ID, key.Signer)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !canResend(req) {
+		return resp, err
+	}
+	prev, ok := c.keyRing.previous(key.ID, now)
+	if !ok {
+		return resp, err
+	}
+
+	c.logf("key %s rejected with 401, retrying with previous key %s", key.ID, prev.ID)
+	drainBody(resp)
+	resp, err = c.doSigned(next, req, prev.ID, prev.Signer)
+	if err == nil && resp.StatusCode < 300 {
+		c.rotated(c.keyRing.reject(key.ID, prev.ID, now))
+	}
+	return resp, err
 }
 
-// generate Signature
-func (r *request) genSignature(date, digest string) (string, error) {
-	if date == "" {
-		return "", errors.New("empty date")
+// doSigned sends copy of req signed with key of keyID. Request rejected
+// with 401 response whose Date header is off by clockSkewThreshold or
+// more is signed again with adjusted clock and retried once.
+func (c *Client) doSigned(next Doer, req *http.Request, keyID string, signer Signer) (*http.Response, error) {
+	offset := c.ClockOffset()
+	signedAt := c.clockNow().Add(offset)
+	signed, err := c.signedCopy(req, keyID, signer, signedAt)
+	if err != nil {
+		return nil, err
 	}
-	if os.Getenv("FORM3_HOST") == "" {
-		return "", errors.New("empty FORM3_HOST env variable")
+	resp, err := next.Do(signed)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !canResend(req) || !c.adjustClock(resp, signedAt, offset) {
+		return resp, err
 	}
-	if r.keyID == "" {
-		return "", errors.New("empty FORM3_KEY_ID env variable")
+
+	retry, err := c.signedCopy(req, keyID, signer, c.now())
+	if err != nil {
+		return resp, nil
 	}
-	if r.keyPath == "" {
-		return "", errors.New("empty FORM3_PRIV_KEY_PATH env variable")
+	drainBody(resp)
+	return next.Do(retry)
+}
+
+// signedCopy returns copy of req with fresh body signed at now.
+func (c *Client) signedCopy(req *http.Request, keyID string, signer Signer, now time.Time) (*http.Request, error) {
+	signed := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		signed.Body = body
+	}
+	if err := signRequest(signed, keyID, signer, c.signOpts, now); err != nil {
+		return nil, fmt.Errorf("form3go: unexpected generating Signature failure: %v", err)
 	}
+	return signed, nil
+}
+
+// canResend reports whether body of req can be sent again.
+func canResend(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// drainBody drains and closes body of resp which is not used.
+func drainBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body.Close()
+}
 
-	signatureStr := "(request-target): " + r.method + " " + r.endpoint + "\n"
-	signatureStr += "host: " + os.Getenv("FORM3_HOST") + "\n"
-	signatureStr += "date: " + date + "\n"
-	if digest != "" {
-		signatureStr += "accept: application/vnd.api+json\n"
-		signatureStr += "content-type: application/vnd.api+json\n"
-		signatureStr += "content-length: " + strconv.Itoa(len(r.data)) + "\n"
-		signatureStr += "digest: " + digest + "\n"
+// signOptions configures digest and signature headers of signed requests.
+// Zero value signs per draft-cavage-http-signatures with SHA-256 Digest.
+type signOptions struct {
+	// digest is digest algorithm, DigestSHA256 when empty.
+	digest string
+	// contentDigest emits RFC 9530 Content-Digest header instead of
+	// Digest header.
+	contentDigest bool
+	// messageSignatures signs per RFC 9421 with Signature-Input and
+	// Signature headers instead of Authorization header.
+	messageSignatures bool
+}
+
+// signRequest adds Date, Digest and Authorization headers to req signed
+// at now, or Content-Digest, Signature-Input and Signature headers as
+// configured by opts. The signature covers exactly the signed headers
+// which are sent, see signedHeaders.
+func signRequest(req *http.Request, keyID string, signer Signer, opts signOptions, now time.Time) error {
+	req.Header.Set("Date", genDateHeader(now))
+	if req.GetBody != nil && req.ContentLength != 0 {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+		if opts.contentDigest || opts.messageSignatures {
+			req.Header.Set("Content-Digest", genContentDigestHeader(opts.digest, data))
+		} else {
+			req.Header.Set("Digest", digestHeader(opts.digest, data))
+		}
+	}
+	if opts.messageSignatures {
+		return signMessage(req, keyID, signer, now)
 	}
 
-	signer, err := loadPrivateKey(r.keyPath)
+	headers := signedHeaders(req)
+	sig, err := genSignature(req, headers, signer)
 	if err != nil {
-		return "", err
+		return err
 	}
-	signed, err := signer.Sign([]byte(signatureStr))
+	authHeader, err := genAuthHeader(keyID, signer.Algorithm(), headers, sig)
 	if err != nil {
-		return "", err
+		return err
 	}
-	sig := base64.StdEncoding.EncodeToString(signed)
-	return sig, nil
+	req.Header.Set("Authorization", authHeader)
+	return nil
 }
 
-// generate Authorization Header
-func (r *request) genAuthHeader(sig string) (string, error) {
-	if sig == "" {
-		return "", errors.New("genAuthHeader: invalid signature")
+// signedHeaders returns names of headers signed for req. (request-target),
+// host and date are always signed, accept, content-type, content-length,
+// digest and content-digest are signed when req carries them.
+func signedHeaders(req *http.Request) []string {
+	headers := []string{"(request-target)", "host", "date"}
+	for _, h := range []string{"accept", "content-type", "content-length", "digest", "content-digest"} {
+		if req.Header.Get(h) != "" {
+			headers = append(headers, h)
+		}
 	}
-	return `Signature keyId="` + r.keyID + `",algorithm="rsa-sha256",header="(request-target) host date accept content-type content-length digest",signature="` + sig + `"`, nil
+	return headers
 }
 
-func loadPrivateKey(path string) (Signer, error) {
-	dat, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+// signingString returns string signed for headers of req as defined by
+// draft-cavage-http-signatures.
+func signingString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var v string
+		switch h {
+		case "(request-target)":
+			v = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			v = req.Host
+			if v == "" {
+				v = req.URL.Host
+			}
+		default:
+			v = req.Header.Get(h)
+		}
+		if v == "" {
+			return "", fmt.Errorf("empty %s header", h)
+		}
+		lines = append(lines, h+": "+v)
 	}
-	return parsePrivateKey(dat)
+	return strings.Join(lines, "\n"), nil
 }
 
-func parsePrivateKey(pemBytes []byte) (Signer, error) {
-	block, _ := pem.Decode(pemBytes)
-	if block == nil {
-		return nil, errors.New("rsa: no key found")
-	}
+// genDateHeader returns Date header of t, always formatted in GMT as
+// required by HTTP.
+func genDateHeader(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
 
-	var rawkey interface{}
-	switch block.Type {
-	case "RSA PRIVATE KEY":
-		rsa, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
+// Digest algorithms of Digest and Content-Digest headers.
+const (
+	DigestSHA256 = "SHA-256"
+	DigestSHA512 = "SHA-512"
+)
+
+// WithDigestAlgorithm sets algorithm of request body digest, DigestSHA256
+// or DigestSHA512. SHA-256 is used by default.
+func WithDigestAlgorithm(algorithm string) Option {
+	return func(c *Client) error {
+		if algorithm != DigestSHA256 && algorithm != DigestSHA512 {
+			return fmt.Errorf("form3go: unsupported digest algorithm %q", algorithm)
 		}
-		rawkey = rsa
-	default:
-		return nil, fmt.Errorf("rsa: unsupported key type %q", block.Type)
+		c.signOpts.digest = algorithm
+		return nil
 	}
-	return newSignerFromKey(rawkey)
 }
 
-type Signer interface {
-	Sign(data []byte) ([]byte, error)
+// WithContentDigest makes client send RFC 9530 Content-Digest header
+// instead of Digest header with request body.
+func WithContentDigest() Option {
+	return func(c *Client) error {
+		c.signOpts.contentDigest = true
+		return nil
+	}
+}
+
+// generate Digest Header
+func genDigestHeader(data []byte) string {
+	return digestHeader(DigestSHA256, data)
 }
 
-func newSignerFromKey(k interface{}) (Signer, error) {
-	var rsaKey Signer
-	switch t := k.(type) {
-	case *rsa.PrivateKey:
-		rsaKey = &rsaPrivateKey{t}
-	default:
-		return nil, fmt.Errorf("rsa: unsupported key type %T", k)
+// digestHeader returns Digest header of data using algorithm,
+// DigestSHA256 when empty.
+func digestHeader(algorithm string, data []byte) string {
+	if algorithm == "" {
+		algorithm = DigestSHA256
 	}
-	return rsaKey, nil
+	return algorithm + "=" + base64.StdEncoding.EncodeToString(digestSum(algorithm, data))
 }
 
-type rsaPrivateKey struct {
-	*rsa.PrivateKey
+// genContentDigestHeader returns RFC 9530 Content-Digest header of data
+// using algorithm, DigestSHA256 when empty.
+func genContentDigestHeader(algorithm string, data []byte) string {
+	if algorithm == "" {
+		algorithm = DigestSHA256
+	}
+	return strings.ToLower(algorithm) + "=:" + base64.StdEncoding.EncodeToString(digestSum(algorithm, data)) + ":"
+}
+
+// digestSum returns digest of data computed with algorithm.
+func digestSum(algorithm string, data []byte) []byte {
+	if strings.EqualFold(algorithm, DigestSHA512) {
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// generate Signature
+func genSignature(req *http.Request, headers []string, signer Signer) (string, error) {
+	signatureStr, err := signingString(req, headers)
+	if err != nil {
+		return "", err
+	}
+	signed, err := signer.Sign([]byte(signatureStr))
+	if err != nil {
+		return "", err
+	}
+	sig := base64.StdEncoding.EncodeToString(signed)
+	return sig, nil
 }
 
-func (r *rsaPrivateKey) Sign(data []byte) ([]byte, error) {
-	h := sha256.New()
-	h.Write(data)
-	d := h.Sum(nil)
-	return rsa.SignPKCS1v15(rand.Reader, r.PrivateKey, crypto.SHA256, d)
+// generate Authorization Header
+func genAuthHeader(keyID, algorithm string, headers []string, sig string) (string, error) {
+	if sig == "" {
+		return "", errors.New("genAuthHeader: invalid signature")
+	}
+	return `Signature keyId="` + keyID + `",algorithm="` + algorithm + `",headers="` + strings.Join(headers, " ") + `",signature="` + sig + `"`, nil
 }
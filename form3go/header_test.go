@@ -1,59 +1,151 @@
 package form3go
 
 import (
-	"os"
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGenDigestHeader(t *testing.T) {
-	requestInfo := request{
-		data: `{"data":{"type":"payments","id":"1234567890","version":0,"organisation_id":"1234567890","attributes":{"amount":"200.00","beneficiary_party":{"account_name":"Mrs Receiving Test","account_number":"71268996","account_number_code":"BBAN","account_with":{"bank_id":"400302","bank_id_code":"GBDSC"}},"currency":"GBP","debtor_party":{"account_name":"Mr Sending Test","account_number":"87654321","account_number_code":"BBAN","account_with":{"bank_id":"1234567890","bank_id_code":"GBDSC"}},"processing_date":"2019-20-5","reference":"Something","payment_scheme":"FPS","scheme_payment_sub_type":"TelephoneBanking","scheme_payment_type":"ImmediatePayment"}}}`,
-	}
+	data := []byte(`{"data":{"type":"payments","id":"1234567890","version":0,"organisation_id":"1234567890","attributes":{"amount":"200.00","beneficiary_party":{"account_name":"Mrs Receiving Test","account_number":"71268996","account_number_code":"BBAN","account_with":{"bank_id":"400302","bank_id_code":"GBDSC"}},"currency":"GBP","debtor_party":{"account_name":"Mr Sending Test","account_number":"87654321","account_number_code":"BBAN","account_with":{"bank_id":"1234567890","bank_id_code":"GBDSC"}},"processing_date":"2019-20-5","reference":"Something","payment_scheme":"FPS","scheme_payment_sub_type":"TelephoneBanking","scheme_payment_type":"ImmediatePayment"}}}`)
+
+	assert.Equal(t, "SHA-256=WllU95a/P37KDBmTedpEIIvVtBgRqDdYrHz06NXDuvk=", genDigestHeader(data))
+}
+
+func TestSigningString(t *testing.T) {
+	// POST signs body headers
+	req, _ := http.NewRequest("POST", "http://accountapi:8080/v1/organisation/accounts", strings.NewReader(testAccountInfo))
+	req.Header.Set("Date", "Wed, 08 Jan 2020 08:52:44 GMT")
+	req.Header.Set("Accept", "application/vnd.api+json")
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	req.Header.Set("Content-Length", "42")
+	req.Header.Set("Digest", "SHA-256=WllU95a/P37KDBmTedpEIIvVtBgRqDdYrHz06NXDuvk=")
+	headers := signedHeaders(req)
+	assert.Equal(t, []string{"(request-target)", "host", "date", "accept", "content-type", "content-length", "digest"}, headers)
+	str, err := signingString(req, headers)
+	assert.Nil(t, err)
+	assert.Equal(t, "(request-target): post /v1/organisation/accounts\n"+
+		"host: accountapi:8080\n"+
+		"date: Wed, 08 Jan 2020 08:52:44 GMT\n"+
+		"accept: application/vnd.api+json\n"+
+		"content-type: application/vnd.api+json\n"+
+		"content-length: 42\n"+
+		"digest: SHA-256=WllU95a/P37KDBmTedpEIIvVtBgRqDdYrHz06NXDuvk=", str)
+
+	// GET signs path with ID and query
+	req, _ = http.NewRequest("DELETE", "http://accountapi:8080/v1/organisation/accounts/9127e265-9605-4b4b-a0e5-3003ea9cc4dc?version=0", nil)
+	req.Header.Set("Date", "Wed, 08 Jan 2020 08:52:44 GMT")
+	headers = signedHeaders(req)
+	assert.Equal(t, []string{"(request-target)", "host", "date"}, headers)
+	str, err = signingString(req, headers)
+	assert.Nil(t, err)
+	assert.Equal(t, "(request-target): delete /v1/organisation/accounts/9127e265-9605-4b4b-a0e5-3003ea9cc4dc?version=0\n"+
+		"host: accountapi:8080\n"+
+		"date: Wed, 08 Jan 2020 08:52:44 GMT", str)
 
-	assert.Equal(t, "SHA-256=WllU95a/P37KDBmTedpEIIvVtBgRqDdYrHz06NXDuvk=", requestInfo.genDigestHeader())
+	// Missing header
+	_, err = signingString(req, []string{"date", "digest"})
+	assert.Equal(t, "empty digest header", err.Error())
 }
 
-func TestGenSignature(t *testing.T) {
-	requestInfo := request{
-		data:     testAccountInfo,
-		method:   "POST",
-		endpoint: "/v1/organisation/accounts",
-		keyID:    os.Getenv("FORM3_KEY_ID"),
-		keyPath:  os.Getenv("FORM3_PRIV_KEY_PATH"),
+func TestSignRequest(t *testing.T) {
+	signer := loadTestKey(t)
+	pub := &signer.PublicKey
+
+	req, _ := http.NewRequest("POST", "http://accountapi:8080/v1/organisation/accounts", bytes.NewReader([]byte(testAccountInfo)))
+	req.Header.Set("Accept", "application/vnd.api+json")
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	assert.Nil(t, signRequest(req, "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", signer, signOptions{}, time.Now()))
+
+	assert.NotEmpty(t, req.Header.Get("Date"))
+	assert.Equal(t, genDigestHeader([]byte(testAccountInfo)), req.Header.Get("Digest"))
+	assert.Equal(t, strconv.Itoa(len(testAccountInfo)), req.Header.Get("Content-Length"))
+
+	m := regexp.MustCompile(`^Signature keyId="([^"]+)",algorithm="rsa-sha256",headers="([^"]+)",signature="([^"]+)"$`).FindStringSubmatch(req.Header.Get("Authorization"))
+	if !assert.Len(t, m, 4) {
+		return
 	}
-	date := "Wed, 08 Jan 2020 03:52:44 EST"
-	digest := requestInfo.genDigestHeader()
-	sig, _ := requestInfo.genSignature(date, digest)
-	assert.Equal(t, "tCLWrCm2Tm2EQ0uoIKmai0cifub+R5XI/huAul1IVWHqNBifgvTFtJc1HT1nNtv85PZzpgdzTqAuZOd1jn1d8EXEQbjC/79koiE1z9REuMdKxIwk5KKmZVRQkaHUAg51mO8uAZRYJrpOnGUROoG4hU4WC0fOwNeBW36XIrpVlYM=", sig)
-
-	// Invalid Key_ID is provided
-	requestInfo.keyID = ""
-	sig, err := requestInfo.genSignature(date, digest)
-	assert.Equal(t, "", sig)
-	assert.Equal(t, "empty FORM3_KEY_ID env variable", err.Error())
-
-	// Invalid Key Path is provided
-	requestInfo.keyID = os.Getenv("FORM3_KEY_ID")
-	requestInfo.keyPath = ""
-	sig, err = requestInfo.genSignature(date, digest)
-	assert.Equal(t, "", sig)
-	assert.Equal(t, "empty FORM3_PRIV_KEY_PATH env variable", err.Error())
+	assert.Equal(t, "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", m[1])
+	assert.Equal(t, "(request-target) host date accept content-type content-length digest", m[2])
+
+	// Signature covers declared headers
+	str, _ := signingString(req, strings.Split(m[2], " "))
+	hash := sha256.Sum256([]byte(str))
+	sig, _ := base64.StdEncoding.DecodeString(m[3])
+	assert.Nil(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig))
 }
 
 func TestGenAuthHeader(t *testing.T) {
-	requestInfo := request{
-		data:     testAccountInfo,
-		method:   "POST",
-		endpoint: "/v1/organisation/accounts",
-		keyID:    os.Getenv("FORM3_KEY_ID"),
-		keyPath:  os.Getenv("FORM3_PRIV_KEY_PATH"),
+	authHeader, err := genAuthHeader("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", "rsa-sha256", []string{"(request-target)", "host", "date"}, "c2lnbmF0dXJl")
+	assert.Nil(t, err)
+	assert.Equal(t, `Signature keyId="75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8",algorithm="rsa-sha256",headers="(request-target) host date",signature="c2lnbmF0dXJl"`, authHeader)
+
+	_, err = genAuthHeader("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", "rsa-sha256", nil, "")
+	assert.Equal(t, "genAuthHeader: invalid signature", err.Error())
+}
+
+func TestSignRequests(t *testing.T) {
+	// Key ID is required
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {}, WithKeyID(""))
+	_, err := client.ListAccounts(0, 1)
+	assert.Equal(t, "form3go: unexpected generating Signature failure: empty key ID", err.Error())
+
+	// Private key is required
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {}, WithPrivateKeyPath(""))
+	_, err = client.ListAccounts(0, 1)
+	assert.Equal(t, "form3go: unexpected generating Signature failure: empty private key", err.Error())
+
+	// Server receives exactly the signed headers
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		m := regexp.MustCompile(`headers="([^"]+)"`).FindStringSubmatch(r.Header.Get("Authorization"))
+		assert.Equal(t, "(request-target) host date accept", m[1])
+		assert.Equal(t, "application/vnd.api+json", r.Header.Get("Accept"))
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	})
+	_, err = client.ListAccounts(0, 1)
+	assert.Nil(t, err)
+}
+
+func BenchmarkSignRequest(b *testing.B) {
+	path := "../test_private_key.pem"
+	newReq := func() *http.Request {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/v1/organisation/accounts", nil)
+		return req
 	}
-	date := "Wed, 08 Jan 2020 03:52:44 EST"
-	digest := requestInfo.genDigestHeader()
-	sig, _ := requestInfo.genSignature(date, digest)
-	authHeader, _ := requestInfo.genAuthHeader(sig)
-	expectedHeader := `Signature keyId="75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8",algorithm="rsa-sha256",header="(request-target) host date accept content-type content-length digest",signature="tCLWrCm2Tm2EQ0uoIKmai0cifub+R5XI/huAul1IVWHqNBifgvTFtJc1HT1nNtv85PZzpgdzTqAuZOd1jn1d8EXEQbjC/79koiE1z9REuMdKxIwk5KKmZVRQkaHUAg51mO8uAZRYJrpOnGUROoG4hU4WC0fOwNeBW36XIrpVlYM="`
-	assert.Equal(t, expectedHeader, authHeader)
+
+	// key is read and parsed for every request
+	b.Run("LoadPerRequest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			signer, err := loadPrivateKey(path, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := signRequest(newReq(), "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", signer, signOptions{}, time.Now()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// key is parsed once by NewClient
+	b.Run("Cached", func(b *testing.B) {
+		kf, err := newKeyFile(path, nil, time.Minute)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			if err := signRequest(newReq(), "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", kf.current(), signOptions{}, time.Now()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
 }
@@ -0,0 +1,269 @@
+package form3go
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageSignatureLabel is label of RFC 9421 signature added by client.
+const messageSignatureLabel = "sig1"
+
+// messageSignatureAlgorithms maps Signer algorithms to RFC 9421
+// algorithm names.
+var messageSignatureAlgorithms = map[string]string{
+	AlgorithmRSASHA256:   "rsa-v1_5-sha256",
+	AlgorithmECDSASHA256: "ecdsa-p256-sha256",
+	AlgorithmECDSASHA384: "ecdsa-p384-sha384",
+	AlgorithmEd25519:     "ed25519",
+}
+
+// WithMessageSignatures makes client sign requests per RFC 9421 HTTP
+// Message Signatures, sending Signature-Input and Signature headers
+// instead of draft-cavage Authorization header. Request body digest is
+// sent in RFC 9530 Content-Digest header.
+func WithMessageSignatures() Option {
+	return func(c *Client) error {
+		c.signOpts.messageSignatures = true
+		return nil
+	}
+}
+
+// messageComponents returns RFC 9421 components signed for req. Method,
+// authority and path are always signed, query when req has one and date,
+// accept, content-type, content-length and content-digest headers when
+// req carries them.
+func messageComponents(req *http.Request) []string {
+	components := []string{"@method", "@authority", "@path"}
+	if req.URL.RawQuery != "" {
+		components = append(components, "@query")
+	}
+	for _, h := range []string{"date", "accept", "content-type", "content-length", "content-digest"} {
+		if req.Header.Get(h) != "" {
+			components = append(components, h)
+		}
+	}
+	return components
+}
+
+// signMessage adds RFC 9421 Signature-Input and Signature headers to req.
+func signMessage(req *http.Request, keyID string, signer Signer, created time.Time) error {
+	alg, ok := messageSignatureAlgorithms[signer.Algorithm()]
+	if !ok {
+		return fmt.Errorf("algorithm %q is not supported by HTTP message signatures", signer.Algorithm())
+	}
+	components := messageComponents(req)
+	params := signatureParamsValue(components, created.Unix(), keyID, alg)
+	base, err := signatureBase(req, components, params)
+	if err != nil {
+		return err
+	}
+	sig, err := signer.Sign([]byte(base))
+	if err != nil {
+		return err
+	}
+	if size := ecdsaKeySize(signer.Algorithm()); size > 0 {
+		if sig, err = ecdsaRawSignature(sig, size); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Signature-Input", messageSignatureLabel+"="+params)
+	req.Header.Set("Signature", messageSignatureLabel+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// signatureParamsValue returns @signature-params value of components
+// signed at created with key of keyID using RFC 9421 algorithm alg.
+func signatureParamsValue(components []string, created int64, keyID, alg string) string {
+	quoted := make([]string, len(components))
+	for i, c := range components {
+		quoted[i] = strconv.Quote(c)
+	}
+	return "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(created, 10) +
+		";keyid=" + strconv.Quote(keyID) + ";alg=" + strconv.Quote(alg)
+}
+
+// signatureBase returns RFC 9421 signature base of components of req.
+func signatureBase(req *http.Request, components []string, params string) (string, error) {
+	lines := make([]string, 0, len(components)+1)
+	for _, c := range components {
+		v, err := componentValue(req, c)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, strconv.Quote(c)+": "+v)
+	}
+	lines = append(lines, `"@signature-params": `+params)
+	return strings.Join(lines, "\n"), nil
+}
+
+// componentValue returns value of RFC 9421 component of req.
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return req.Method, nil
+	case "@authority":
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		return strings.ToLower(host), nil
+	case "@path":
+		if p := req.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	case "@request-target":
+		return req.URL.RequestURI(), nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("unsupported %s component", component)
+	}
+	values := req.Header.Values(component)
+	if len(values) == 0 {
+		return "", fmt.Errorf("empty %s header", component)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// ecdsaKeySize returns size in bytes of ECDSA key of algorithm, or 0 if
+// algorithm is not ECDSA one.
+func ecdsaKeySize(algorithm string) int {
+	switch algorithm {
+	case AlgorithmECDSASHA256:
+		return 32
+	case AlgorithmECDSASHA384:
+		return 48
+	}
+	return 0
+}
+
+// ecdsaSignature is ASN.1 structure of ECDSA signature.
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// ecdsaRawSignature converts ASN.1 DER ECDSA signature to fixed size
+// r||s form required by RFC 9421.
+func ecdsaRawSignature(der []byte, size int) ([]byte, error) {
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+		return nil, errors.New("malformed ecdsa signature")
+	}
+	if sig.R.BitLen() > size*8 || sig.S.BitLen() > size*8 {
+		return nil, errors.New("ecdsa signature does not match key size")
+	}
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// ecdsaDERSignature converts r||s ECDSA signature to ASN.1 DER form.
+func ecdsaDERSignature(raw []byte, size int) ([]byte, error) {
+	if len(raw) != 2*size {
+		return nil, errors.New("ecdsa signature does not match key size")
+	}
+	return asn1.Marshal(ecdsaSignature{
+		R: new(big.Int).SetBytes(raw[:size]),
+		S: new(big.Int).SetBytes(raw[size:]),
+	})
+}
+
+// messageSignature is parsed RFC 9421 signature.
+type messageSignature struct {
+	components []string
+	params     string
+	created    int64
+	hasCreated bool
+	keyID      string
+	alg        string
+	signature  []byte
+}
+
+// parseMessageSignature parses the first signature of Signature-Input
+// header and its counterpart of Signature header.
+func parseMessageSignature(input, signature string) (messageSignature, error) {
+	var ms messageSignature
+	eq := strings.IndexByte(input, '=')
+	if eq < 0 {
+		return ms, fmt.Errorf("%w: malformed Signature-Input header", ErrSignatureInvalid)
+	}
+	label := strings.TrimSpace(input[:eq])
+	rest := strings.TrimSpace(input[eq+1:])
+	// value ends at comma outside of quotes, which starts next member
+	quoted := false
+	for i, r := range rest {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ',' && !quoted {
+			rest = rest[:i]
+			break
+		}
+	}
+	if !strings.HasPrefix(rest, "(") {
+		return ms, fmt.Errorf("%w: malformed Signature-Input header", ErrSignatureInvalid)
+	}
+	end := strings.IndexByte(rest, ')')
+	if end < 0 {
+		return ms, fmt.Errorf("%w: malformed Signature-Input header", ErrSignatureInvalid)
+	}
+	ms.params = rest
+	for _, c := range strings.Fields(rest[1:end]) {
+		name, err := strconv.Unquote(c)
+		if err != nil {
+			return ms, fmt.Errorf("%w: malformed component %s", ErrSignatureInvalid, c)
+		}
+		ms.components = append(ms.components, name)
+	}
+	for _, p := range strings.Split(rest[end+1:], ";") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "created":
+			created, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return ms, fmt.Errorf("%w: malformed created parameter", ErrSignatureInvalid)
+			}
+			ms.created, ms.hasCreated = created, true
+		case "keyid":
+			ms.keyID, _ = strconv.Unquote(kv[1])
+		case "alg":
+			ms.alg, _ = strconv.Unquote(kv[1])
+		}
+	}
+	if ms.keyID == "" {
+		return ms, fmt.Errorf("%w: keyid is required", ErrSignatureInvalid)
+	}
+
+	for _, member := range strings.Split(signature, ",") {
+		member = strings.TrimSpace(member)
+		if !strings.HasPrefix(member, label+"=:") || !strings.HasSuffix(member, ":") || len(member) < len(label)+3 {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(member[len(label)+2 : len(member)-1])
+		if err != nil {
+			return ms, fmt.Errorf("%w: signature is not base64: %v", ErrSignatureInvalid, err)
+		}
+		ms.signature = sig
+		return ms, nil
+	}
+	return ms, fmt.Errorf("%w: no %s signature", ErrSignatureMissing, label)
+}
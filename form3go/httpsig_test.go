@@ -0,0 +1,233 @@
+package form3go
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestHeaders(t *testing.T) {
+	// RFC 9530 examples
+	data := []byte(`{"hello": "world"}`)
+	assert.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", genContentDigestHeader("", data))
+	assert.Equal(t, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:", genContentDigestHeader(DigestSHA512, data))
+	assert.Equal(t, "SHA-512=WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==", digestHeader(DigestSHA512, data))
+	assert.Equal(t, genDigestHeader(data), digestHeader("", data))
+
+	assert.Nil(t, checkDigest(genContentDigestHeader(DigestSHA512, data), data, true))
+	assert.Nil(t, checkDigest("unixsum=:AAA=:, "+genContentDigestHeader("", data), data, true))
+	assert.Nil(t, checkDigest(digestHeader(DigestSHA512, data), data, false))
+	assert.True(t, errors.Is(checkDigest(genContentDigestHeader("", data), []byte("{}"), true), ErrDigestMismatch))
+	assert.True(t, errors.Is(checkDigest("md5=:AAA=:", data, true), ErrDigestMismatch))
+	assert.True(t, errors.Is(checkDigest("sha-256=AAA=", data, true), ErrDigestMismatch))
+
+	_, err := NewClient(WithBaseURL("http://localhost:8080"), WithDigestAlgorithm("MD5"))
+	assert.NotNil(t, err)
+}
+
+func TestSignatureBase(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://Example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest", genContentDigestHeader(DigestSHA512, []byte(`{"hello": "world"}`)))
+	req.Header.Set("Content-Length", "18")
+
+	components := messageComponents(req)
+	assert.Equal(t, []string{"@method", "@authority", "@path", "@query", "date", "content-type", "content-length", "content-digest"}, components)
+	params := signatureParamsValue(components, 1618884473, "test-key-rsa-pss", "rsa-v1_5-sha256")
+	base, err := signatureBase(req, components, params)
+	assert.Nil(t, err)
+	assert.Equal(t, `"@method": POST
+"@authority": example.com
+"@path": /foo
+"@query": ?param=Value&Pet=dog
+"date": Tue, 20 Apr 2021 02:07:55 GMT
+"content-type": application/json
+"content-length": 18
+"content-digest": sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:
+"@signature-params": ("@method" "@authority" "@path" "@query" "date" "content-type" "content-length" "content-digest");created=1618884473;keyid="test-key-rsa-pss";alg="rsa-v1_5-sha256"`, base)
+
+	_, err = signatureBase(req, []string{"accept"}, params)
+	assert.NotNil(t, err)
+	_, err = signatureBase(req, []string{"@scheme"}, params)
+	assert.NotNil(t, err)
+}
+
+func TestECDSARawSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := ecdsa.SignASN1(rand.Reader, key, make([]byte, 48))
+	assert.Nil(t, err)
+	raw, err := ecdsaRawSignature(der, 48)
+	assert.Nil(t, err)
+	assert.Len(t, raw, 96)
+	back, err := ecdsaDERSignature(raw, 48)
+	assert.Nil(t, err)
+	assert.Equal(t, der, back)
+
+	_, err = ecdsaRawSignature(der, 32)
+	assert.NotNil(t, err)
+	_, err = ecdsaRawSignature([]byte("invalid"), 48)
+	assert.NotNil(t, err)
+	_, err = ecdsaDERSignature(raw, 32)
+	assert.NotNil(t, err)
+}
+
+// newMessageSignedRequest returns request signed per RFC 9421 as if
+// received by server.
+func newMessageSignedRequest(t *testing.T, signer Signer, method, target, body string) *http.Request {
+	req, _ := http.NewRequest(method, "http://accountapi:8080"+target, bytes.NewReader([]byte(body)))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/vnd.api+json")
+	} else {
+		req.Body, req.GetBody, req.ContentLength = nil, nil, 0
+	}
+	assert.Nil(t, signRequest(req, "test-key", signer, signOptions{messageSignatures: true, digest: DigestSHA512}, time.Now()))
+
+	srvReq := httptest.NewRequest(method, target, strings.NewReader(body))
+	srvReq.Host = "accountapi:8080"
+	srvReq.Header = req.Header.Clone()
+	return srvReq
+}
+
+func TestVerifyMessageSignature(t *testing.T) {
+	for algorithm, key := range genTestKeys(t) {
+		signer, err := NewCryptoSigner(key)
+		assert.Nil(t, err)
+		v := &Verifier{Keys: StaticKeyStore{"test-key": key.Public()}}
+
+		req := newMessageSignedRequest(t, signer, "POST", "/v1/organisation/accounts", testAccountInfo)
+		assert.Empty(t, req.Header.Get("Authorization"))
+		assert.Empty(t, req.Header.Get("Digest"))
+		assert.True(t, strings.HasPrefix(req.Header.Get("Content-Digest"), "sha-512=:"))
+		assert.Regexp(t, `^sig1=\("@method" "@authority" "@path" "date" "content-type" "content-length" "content-digest"\);created=\d+;keyid="test-key";alg="`+messageSignatureAlgorithms[algorithm]+`"$`, req.Header.Get("Signature-Input"))
+		keyID, err := v.Verify(req)
+		assert.Nil(t, err, algorithm)
+		assert.Equal(t, "test-key", keyID)
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, testAccountInfo, string(body))
+
+		req = newMessageSignedRequest(t, signer, "DELETE", "/v1/organisation/accounts/9127e265-9605-4b4b-a0e5-3003ea9cc4dc?version=0", "")
+		_, err = v.Verify(req)
+		assert.Nil(t, err, algorithm)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	signer, err := NewCryptoSigner(key)
+	assert.Nil(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+	v := &Verifier{Keys: StaticKeyStore{"test-key": key.Public(), "other-key": other.Public()}}
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+		err    error
+	}{
+		{"missing signature", func(req *http.Request) { req.Header.Del("Signature") }, ErrSignatureMissing},
+		{"tampered body", func(req *http.Request) {
+			req.Body = ioutil.NopCloser(strings.NewReader(strings.Replace(testAccountInfo, "GB", "DE", 1)))
+		}, ErrDigestMismatch},
+		{"tampered method", func(req *http.Request) { req.Method = "PUT" }, ErrSignatureInvalid},
+		{"tampered path", func(req *http.Request) { req.URL.Path = "/v1/organisation/accounts/other" }, ErrSignatureInvalid},
+		{"added query", func(req *http.Request) { req.URL.RawQuery = "version=1" }, ErrSignatureInvalid},
+		{"tampered host", func(req *http.Request) { req.Host = "evil:8080" }, ErrSignatureInvalid},
+		{"unknown key", func(req *http.Request) {
+			req.Header.Set("Signature-Input", strings.Replace(req.Header.Get("Signature-Input"), "test-key", "unknown", 1))
+		}, ErrUnknownKey},
+		{"wrong key", func(req *http.Request) {
+			req.Header.Set("Signature-Input", strings.Replace(req.Header.Get("Signature-Input"), "test-key", "other-key", 1))
+		}, ErrSignatureInvalid},
+		{"unsigned digest", func(req *http.Request) {
+			req.Header.Set("Signature-Input", strings.Replace(req.Header.Get("Signature-Input"), ` "content-digest"`, "", 1))
+		}, ErrSignatureInvalid},
+		{"unsigned authority", func(req *http.Request) {
+			req.Header.Set("Signature-Input", strings.Replace(req.Header.Get("Signature-Input"), ` "@authority"`, "", 1))
+		}, ErrSignatureInvalid},
+		{"stale created", func(req *http.Request) {
+			input := req.Header.Get("Signature-Input")
+			req.Header.Set("Signature-Input", regexp.MustCompile(`created=\d+`).ReplaceAllString(input, "created=1618884473"))
+		}, ErrClockSkew},
+		{"malformed input", func(req *http.Request) { req.Header.Set("Signature-Input", `sig1="@method"`) }, ErrSignatureInvalid},
+		{"other label", func(req *http.Request) {
+			req.Header.Set("Signature", strings.Replace(req.Header.Get("Signature"), "sig1=", "sig2=", 1))
+		}, ErrSignatureMissing},
+	}
+	for _, tt := range tests {
+		req := newMessageSignedRequest(t, signer, "POST", "/v1/organisation/accounts", testAccountInfo)
+		tt.tamper(req)
+		_, err := v.Verify(req)
+		assert.True(t, errors.Is(err, tt.err), "%s: %v", tt.name, err)
+	}
+
+	// first of multiple signatures is verified
+	req := newMessageSignedRequest(t, signer, "GET", "/v1/organisation/accounts", "")
+	req.Header.Set("Signature-Input", req.Header.Get("Signature-Input")+`, proxy=("@method");created=1;keyid="proxy, key"`)
+	req.Header.Set("Signature", req.Header.Get("Signature")+", proxy=:AAAA:")
+	_, err = v.Verify(req)
+	assert.Nil(t, err)
+}
+
+func TestClientDigestAndSignatureOptions(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	v := &Verifier{Keys: StaticKeyStore{"test-key": key.Public()}}
+	var header http.Header
+	ts := httptest.NewServer(v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(testAccountInfo))
+	})))
+	defer ts.Close()
+
+	account := Account{}
+	assert.Nil(t, json.Unmarshal([]byte(testAccountInfo), &account))
+	tests := []struct {
+		name  string
+		opts  []Option
+		check func(h http.Header)
+	}{
+		{"SHA-512 digest", []Option{WithDigestAlgorithm(DigestSHA512)}, func(h http.Header) {
+			assert.True(t, strings.HasPrefix(h.Get("Digest"), "SHA-512="))
+			assert.Contains(t, h.Get("Authorization"), " digest")
+		}},
+		{"content digest", []Option{WithContentDigest()}, func(h http.Header) {
+			assert.Empty(t, h.Get("Digest"))
+			assert.True(t, strings.HasPrefix(h.Get("Content-Digest"), "sha-256=:"))
+			assert.Contains(t, h.Get("Authorization"), " content-digest")
+		}},
+		{"message signatures", []Option{WithMessageSignatures(), WithDigestAlgorithm(DigestSHA512)}, func(h http.Header) {
+			assert.Empty(t, h.Get("Authorization"))
+			assert.True(t, strings.HasPrefix(h.Get("Content-Digest"), "sha-512=:"))
+			assert.Contains(t, h.Get("Signature-Input"), `alg="ecdsa-p256-sha256"`)
+		}},
+	}
+	for _, tt := range tests {
+		opts := append([]Option{WithBaseURL(ts.URL), WithKeyID("test-key"), WithCryptoSigner(key)}, tt.opts...)
+		client, err := NewClient(opts...)
+		assert.Nil(t, err)
+		_, err = client.CreateAccount(account)
+		assert.Nil(t, err, tt.name)
+		tt.check(header)
+	}
+
+	// algorithms unknown to RFC 9421 cannot be used
+	client, err := NewClient(WithBaseURL(ts.URL), WithKeyID("test-key"), WithMessageSignatures(),
+		WithSigner(&SocketSigner{SignatureAlgorithm: "hmac-sha256"}))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,84 @@
+package form3go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// existingAccount returns existing account with ID of acct, whose
+// creation failed with conflict err, if it has attributes of acct.
+func (c *Client) existingAccount(ctx context.Context, acct Account, err error) (Account, error) {
+	existing, fetchErr := c.FetchAccountWithContext(ctx, acct.AccountData.ID)
+	if fetchErr != nil {
+		// conflict is not caused by account ID, e.g. by duplicate
+		// account number
+		if IsNotFound(fetchErr) {
+			return Account{}, err
+		}
+		return Account{}, fetchErr
+	}
+	diff, diffErr := accountDiff(acct, existing)
+	if diffErr != nil {
+		return Account{}, diffErr
+	}
+	if len(diff) > 0 {
+		var apiErr *APIError
+		errors.As(err, &apiErr)
+		return Account{}, &AccountConflictError{ID: acct.AccountData.ID, Diff: diff, Err: apiErr}
+	}
+	return existing, nil
+}
+
+// accountDiff returns fields of requested account which differ in
+// existing one. Fields not sent in request, e.g. version or empty
+// attributes, are not compared.
+func accountDiff(requested, existing Account) ([]FieldDiff, error) {
+	req, err := accountFields(requested)
+	if err != nil {
+		return nil, err
+	}
+	ex, err := accountFields(existing)
+	if err != nil {
+		return nil, err
+	}
+	var diff []FieldDiff
+	for field, value := range req {
+		if field == "version" {
+			continue
+		}
+		if !reflect.DeepEqual(value, ex[field]) {
+			diff = append(diff, FieldDiff{Field: field, Requested: value, Existing: ex[field]})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Field < diff[j].Field })
+	return diff, nil
+}
+
+// accountFields returns JSON values of fields of account data by path,
+// with attributes and relationships flattened, e.g. "attributes.iban".
+func accountFields(acct Account) (map[string]interface{}, error) {
+	data, err := json.Marshal(acct.AccountData)
+	if err != nil {
+		return nil, fmt.Errorf("form3go: unexpected JSON marshal failure: %v", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("form3go: unexpected JSON unmarshal failure: %v", err)
+	}
+	fields := map[string]interface{}{}
+	for name, value := range values {
+		nested, ok := value.(map[string]interface{})
+		if !ok || (name != "attributes" && name != "relationships") {
+			fields[name] = value
+			continue
+		}
+		for n, v := range nested {
+			fields[name+"."+n] = v
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,64 @@
+package form3go
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotentCreate(t *testing.T) {
+	requested := newBulkAccounts(t, 1)[0]
+	existing := requested
+	existing.AccountData.Version = 3
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error_message":"Account cannot be created as it violates a duplicate constraint"}`))
+			return
+		}
+		if existing.AccountData.ID == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(existing)
+	}, WithIdempotentCreate())
+
+	// identical account exists
+	acct, err := client.CreateAccount(requested)
+	assert.Nil(t, err)
+	assert.Equal(t, existing, acct)
+
+	// account with different attributes exists
+	existing.AccountData.Attributes.BankID = "400300"
+	existing.AccountData.Attributes.JointAccount = nil
+	_, err = client.CreateAccount(requested)
+	var conflict *AccountConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, requested.AccountData.ID, conflict.ID)
+	assert.Equal(t, []FieldDiff{
+		{Field: "attributes.bank_id", Requested: "D00300", Existing: "400300"},
+		{Field: "attributes.joint_account", Requested: false, Existing: nil},
+	}, conflict.Diff)
+	assert.True(t, errors.Is(err, ErrAccountConflict))
+	assert.True(t, errors.Is(err, ErrCreateAccount))
+	assert.True(t, IsConflict(err))
+	assert.Equal(t, "form3go: account "+requested.AccountData.ID+" exists with different attributes: "+
+		"attributes.bank_id (requested D00300, existing 400300), attributes.joint_account (requested false, existing <nil>)", err.Error())
+
+	// conflict is not caused by account ID
+	existing = Account{}
+	_, err = client.CreateAccount(requested)
+	assert.False(t, errors.Is(err, ErrAccountConflict))
+	assert.True(t, IsConflict(err))
+
+	// idempotent mode is off by default
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		w.WriteHeader(http.StatusConflict)
+	})
+	_, err = client.CreateAccount(requested)
+	assert.True(t, IsConflict(err))
+}
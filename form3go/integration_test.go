@@ -4,8 +4,6 @@ package form3go
 
 import (
 	"encoding/json"
-	"net/http"
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,28 +14,25 @@ func TestIntegration(t *testing.T) {
 	_ = json.Unmarshal([]byte(testAccountInfo), account)
 
 	// Create account
-	client := Client{
-		PubKeyID:    os.Getenv("FORM3_KEY_ID"),
-		PrivKeyPath: os.Getenv("FORM3_PRIV_KEY_PATH"),
-		HttpClient:  http.Client{},
-	}
+	client, err := NewClientFromEnv()
+	assert.Nil(t, err)
 	acct, err := client.CreateAccount(*account)
 	assert.Nil(t, err)
-	assert.Equal(t, *account, acct)
+	assert.Equal(t, *account, withoutServerFields(acct)[0])
 
 	// Fetch account
 	acct, err = client.FetchAccount(account.AccountData.ID)
 	assert.Nil(t, err)
-	assert.Equal(t, *account, acct)
+	assert.Equal(t, *account, withoutServerFields(acct)[0])
 
 	// List accounts
 	accts, err := client.ListAccounts(0, 1)
 	accounts := []Account{}
 	accounts = append(accounts, *account)
 	assert.Nil(t, err)
-	assert.Equal(t, accounts, accts)
+	assert.Equal(t, accounts, withoutServerFields(accts...))
 
 	// Delete account
-	err = client.DeleteAccount(account.AccountData.ID, "0")
+	err = client.DeleteAccount(acct.AccountData.ID, acct.AccountData.Version)
 	assert.Nil(t, err)
 }
@@ -0,0 +1,134 @@
+package form3go
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// defaultPageSize is page size used by IterateAccounts when none is
+// given.
+const defaultPageSize = 100
+
+// Links are JSON:API pagination links of list response.
+type Links struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Prev  string `json:"prev"`
+	Next  string `json:"next"`
+	Last  string `json:"last"`
+}
+
+// Page is metadata of page of accounts.
+type Page struct {
+	// Number and Size of page as requested.
+	Number int
+	Size   int
+	// Links of page as returned by server.
+	Links Links
+}
+
+// AccountIterator iterates over accounts page by page, following next
+// links of list responses. It is created by IterateAccounts:
+//
+//	it := client.IterateAccounts(100)
+//	for it.Next(ctx) {
+//		acct := it.Account()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle error
+//	}
+//
+// Iteration may be stopped at any time, pages are fetched only when
+// accounts of previous page are consumed. AccountIterator is not safe
+// for concurrent use.
+type AccountIterator struct {
+	c        *Client
+	query    url.Values
+	page     Page
+	accounts []Account
+	account  Account
+	done     bool
+	err      error
+}
+
+// IterateAccounts returns iterator over all accounts fetched in pages of
+// pageSize accounts. Default page size of 100 is used if pageSize is not
+// positive.
+func (c *Client) IterateAccounts(pageSize int) *AccountIterator {
+	return c.IterateAccountsWithOptions(ListAccountsOptions{PageSize: pageSize})
+}
+
+// IterateAccountsWithOptions returns iterator over accounts matching
+// opts, starting from page opts.PageNumber. Default page size of 100 is
+// used if opts.PageSize is not positive.
+func (c *Client) IterateAccountsWithOptions(opts ListAccountsOptions) *AccountIterator {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	return &AccountIterator{c: c, query: opts.query()}
+}
+
+// Next advances iterator to next account, fetching next page when
+// accounts of current one are consumed. It returns false when there are
+// no more accounts or fetching page failed, see Err. Cancelling ctx
+// aborts the request.
+func (it *AccountIterator) Next(ctx context.Context) bool {
+	for len(it.accounts) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch(ctx)
+	}
+	it.account, it.accounts = it.accounts[0], it.accounts[1:]
+	return true
+}
+
+// Account returns current account.
+func (it *AccountIterator) Account() Account {
+	return it.account
+}
+
+// Err returns error which stopped iteration, or nil if iteration
+// finished or is still in progress.
+func (it *AccountIterator) Err() error {
+	return it.err
+}
+
+// Page returns metadata of page of current account.
+func (it *AccountIterator) Page() Page {
+	return it.page
+}
+
+// fetch fetches page of it.query and prepares query of the next one.
+func (it *AccountIterator) fetch(ctx context.Context) {
+	accounts, links, err := it.c.listAccounts(ctx, it.query)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.page = Page{Links: links}
+	it.page.Number, _ = strconv.Atoi(it.query.Get("page[number]"))
+	it.page.Size, _ = strconv.Atoi(it.query.Get("page[size]"))
+	it.accounts = accounts
+	if links.Next == "" || len(accounts) == 0 {
+		it.done = true
+		return
+	}
+	// Only query of next link is used, so that signed requests are sent
+	// to client base URL regardless of host in links.
+	next, err := url.Parse(links.Next)
+	if err != nil {
+		it.err = fmt.Errorf("form3go: invalid next link %q: %v", links.Next, err)
+		it.done = true
+		return
+	}
+	query := next.Query()
+	if query.Encode() == it.query.Encode() {
+		it.err = fmt.Errorf("form3go: next link %q points to current page", links.Next)
+		it.done = true
+		return
+	}
+	it.query = query
+}
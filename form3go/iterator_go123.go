@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package form3go
+
+import (
+	"context"
+	"iter"
+)
+
+// AllAccounts returns iterator over all accounts fetched in pages of
+// pageSize accounts, see IterateAccounts. Error which stops iteration is
+// yielded with zero Account as the last pair:
+//
+//	for acct, err := range client.AllAccounts(ctx, 100) {
+//		if err != nil {
+//			// handle error
+//		}
+//	}
+func (c *Client) AllAccounts(ctx context.Context, pageSize int) iter.Seq2[Account, error] {
+	return func(yield func(Account, error) bool) {
+		it := c.IterateAccounts(pageSize)
+		for it.Next(ctx) {
+			if !yield(it.Account(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			yield(Account{}, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package form3go
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllAccounts(t *testing.T) {
+	var requests []string
+	client := newPagingClient(t, 5, &requests)
+
+	var ids []string
+	for acct, err := range client.AllAccounts(context.Background(), 2) {
+		assert.Nil(t, err)
+		ids = append(ids, acct.AccountData.ID)
+	}
+	assert.Equal(t, []string{"account-0", "account-1", "account-2", "account-3", "account-4"}, ids)
+
+	// early termination
+	requests = nil
+	for acct := range client.AllAccounts(context.Background(), 2) {
+		if acct.AccountData.ID == "account-1" {
+			break
+		}
+	}
+	assert.Equal(t, 1, len(requests))
+
+	// error is yielded last
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	var errs []error
+	for _, err := range client.AllAccounts(context.Background(), 2) {
+		errs = append(errs, err)
+	}
+	assert.Equal(t, 1, len(errs))
+	assert.True(t, IsUnauthorized(errs[0]))
+}
@@ -0,0 +1,118 @@
+package form3go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPagingClient returns client of server listing total accounts in
+// pages, with next link on every page but the last one.
+func newPagingClient(t *testing.T, total int, requests *[]string) *Client {
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RawQuery)
+		number, _ := strconv.Atoi(r.URL.Query().Get("page[number]"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("page[size]"))
+		page := struct {
+			Data  []Data `json:"data"`
+			Links Links  `json:"links"`
+		}{Data: []Data{}}
+		for i := number * size; i < (number+1)*size && i < total; i++ {
+			page.Data = append(page.Data, Data{ID: fmt.Sprintf("account-%d", i)})
+		}
+		page.Links.Self = "https://api.form3.tech" + r.URL.String()
+		if (number+1)*size < total {
+			page.Links.Next = fmt.Sprintf("https://api.form3.tech%s?page%%5Bnumber%%5D=%d&page%%5Bsize%%5D=%d", acctURL, number+1, size)
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	})
+}
+
+func TestAccountIterator(t *testing.T) {
+	var requests []string
+	client := newPagingClient(t, 5, &requests)
+
+	it := client.IterateAccounts(2)
+	var ids []string
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Account().AccountData.ID)
+		assert.Equal(t, (len(ids)-1)/2, it.Page().Number)
+		assert.Equal(t, 2, it.Page().Size)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"account-0", "account-1", "account-2", "account-3", "account-4"}, ids)
+	assert.Equal(t, 3, len(requests))
+	assert.Equal(t, "", it.Page().Links.Next)
+	assert.False(t, it.Next(context.Background()))
+
+	// pages are fetched only when needed
+	requests = nil
+	it = client.IterateAccounts(0)
+	assert.True(t, it.Next(context.Background()))
+	assert.Equal(t, []string{"page%5Bnumber%5D=0&page%5Bsize%5D=100"}, requests)
+
+	// empty list
+	client = newPagingClient(t, 0, &requests)
+	it = client.IterateAccounts(2)
+	assert.False(t, it.Next(context.Background()))
+	assert.Nil(t, it.Err())
+}
+
+func TestAccountIteratorError(t *testing.T) {
+	requests := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"account-0"}],"links":{"next":"/v1/organisation/accounts?page%5Bnumber%5D=1&page%5Bsize%5D=1"}}`))
+	})
+
+	it := client.IterateAccounts(1)
+	assert.True(t, it.Next(context.Background()))
+	assert.False(t, it.Next(context.Background()))
+	assert.True(t, IsServerError(it.Err()))
+	assert.True(t, errors.Is(it.Err(), ErrListAccounts))
+	assert.False(t, it.Next(context.Background()))
+	assert.Equal(t, 2, requests)
+
+	// next link pointing to current page
+	requests = 0
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"data":[{"id":"account-0"}],"links":{"next":"/v1/organisation/accounts?page%5Bsize%5D=1&page%5Bnumber%5D=0"}}`))
+	})
+	it = client.IterateAccounts(1)
+	assert.True(t, it.Next(context.Background()))
+	assert.False(t, it.Next(context.Background()))
+	assert.NotNil(t, it.Err())
+	assert.Equal(t, 1, requests)
+
+	// cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it = client.IterateAccounts(1)
+	assert.False(t, it.Next(ctx))
+	assert.True(t, errors.Is(it.Err(), context.Canceled))
+}
+
+func TestAccountIteratorOptions(t *testing.T) {
+	var requests []string
+	client := newPagingClient(t, 5, &requests)
+
+	it := client.IterateAccountsWithOptions(ListAccountsOptions{Country: "GB", PageNumber: 1, PageSize: 3})
+	var ids []string
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Account().AccountData.ID)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"account-3", "account-4"}, ids)
+	assert.Equal(t, []string{"filter%5Bcountry%5D=GB&page%5Bnumber%5D=1&page%5Bsize%5D=3"}, requests)
+}
@@ -0,0 +1,232 @@
+package form3go
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// keyRejectTTL is how long key rejected with 401 response is skipped
+// after request signed with previous key succeeded.
+const keyRejectTTL = time.Minute
+
+// Key is signing key of KeyRing.
+type Key struct {
+	// ID is public key ID used in Authorization header.
+	ID string
+	// Signer signs requests with the key.
+	Signer Signer
+	// NotBefore is time key becomes active. Zero means always.
+	NotBefore time.Time
+	// NotAfter is time key expires. Zero means never.
+	NotAfter time.Time
+}
+
+// active reports whether k is active at t.
+func (k Key) active(t time.Time) bool {
+	return !t.Before(k.NotBefore) && (k.NotAfter.IsZero() || t.Before(k.NotAfter))
+}
+
+// KeyRotation reason values.
+const (
+	// RotationActivated is reason of rotation to newly activated key or
+	// from expired key.
+	RotationActivated = "activated"
+	// RotationFallback is reason of rotation to previous key after
+	// request signed with current key was rejected with 401 response.
+	RotationFallback = "fallback"
+)
+
+// KeyRotation is event of client switching signing key.
+type KeyRotation struct {
+	// From and To are IDs of previous and new key.
+	From, To string
+	// Reason is RotationActivated or RotationFallback.
+	Reason string
+	// At is time of rotation.
+	At time.Time
+}
+
+// KeyRing holds signing keys valid at overlapping time windows during
+// key rotation. Requests are signed with the active key activated most
+// recently, if it is rejected with 401 response, request is retried once
+// with the previous active key. KeyRing is safe for concurrent use.
+type KeyRing struct {
+	mu       sync.Mutex
+	keys     []Key
+	rejected map[string]time.Time
+	lastID   string
+}
+
+// NewKeyRing creates key ring of given keys.
+func NewKeyRing(keys ...Key) (*KeyRing, error) {
+	r := &KeyRing{rejected: map[string]time.Time{}}
+	for _, k := range keys {
+		if err := r.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add adds key to r, replacing key of the same ID.
+func (r *KeyRing) Add(k Key) error {
+	if k.ID == "" {
+		return errors.New("form3go: key ID is required")
+	}
+	if k.Signer == nil {
+		return errors.New("form3go: key signer is required")
+	}
+	if !k.NotAfter.IsZero() && !k.NotAfter.After(k.NotBefore) {
+		return errors.New("form3go: key NotAfter must be after NotBefore")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remove(k.ID)
+	r.keys = append(r.keys, k)
+	// newest keys first
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].NotBefore.After(r.keys[j].NotBefore)
+	})
+	return nil
+}
+
+// Remove removes key of given ID from r.
+func (r *KeyRing) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remove(id)
+}
+
+func (r *KeyRing) remove(id string) {
+	for i, k := range r.keys {
+		if k.ID == id {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			break
+		}
+	}
+	delete(r.rejected, id)
+}
+
+// Current returns key requests are signed with at t.
+func (r *KeyRing) Current(t time.Time) (Key, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current(t)
+}
+
+func (r *KeyRing) current(t time.Time) (Key, bool) {
+	var fallback *Key
+	for i, k := range r.keys {
+		if !k.active(t) {
+			continue
+		}
+		if until, ok := r.rejected[k.ID]; ok && t.Before(until) {
+			if fallback == nil {
+				fallback = &r.keys[i]
+			}
+			continue
+		}
+		return k, true
+	}
+	// rejected key is still better than none
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Key{}, false
+}
+
+// previous returns active key activated before key of given ID.
+func (r *KeyRing) previous(id string, t time.Time) (Key, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := false
+	for _, k := range r.keys {
+		if k.ID == id {
+			seen = true
+			continue
+		}
+		if seen && k.active(t) {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// next returns key requests are signed with at t, along with rotation
+// event when it differs from key returned last time.
+func (r *KeyRing) next(t time.Time) (Key, *KeyRotation, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.current(t)
+	if !ok {
+		return Key{}, nil, false
+	}
+	var rotation *KeyRotation
+	if r.lastID != "" && r.lastID != k.ID {
+		rotation = &KeyRotation{From: r.lastID, To: k.ID, Reason: RotationActivated, At: t}
+	}
+	r.lastID = k.ID
+	return k, rotation, true
+}
+
+// reject skips key of id in favour of key fallback was signed with until
+// keyRejectTTL elapses.
+func (r *KeyRing) reject(id, fallback string, t time.Time) *KeyRotation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejected[id] = t.Add(keyRejectTTL)
+	r.lastID = fallback
+	return &KeyRotation{From: id, To: fallback, Reason: RotationFallback, At: t}
+}
+
+// WithKeyRing sets key ring requests are signed with. It takes precedence
+// over WithKeyID and private key options.
+func WithKeyRing(r *KeyRing) Option {
+	return func(c *Client) error {
+		if r == nil {
+			return errors.New("form3go: key ring is nil")
+		}
+		c.keyRing = r
+		return nil
+	}
+}
+
+// WithKeyRotationHook sets function called whenever client switches
+// signing key of key ring.
+func WithKeyRotationHook(fn func(KeyRotation)) Option {
+	return func(c *Client) error {
+		c.onRotate = fn
+		return nil
+	}
+}
+
+// WithLogger sets logger of client events, e.g. key rotations.
+// Nothing is logged by default.
+func WithLogger(l *log.Logger) Option {
+	return func(c *Client) error {
+		c.logger = l
+		return nil
+	}
+}
+
+// logf logs client event when logger is set.
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf("form3go: "+format, args...)
+	}
+}
+
+// rotated reports key rotation event.
+func (c *Client) rotated(ev *KeyRotation) {
+	if ev == nil {
+		return
+	}
+	c.logf("signing key rotated from %s to %s (%s)", ev.From, ev.To, ev.Reason)
+	if c.onRotate != nil {
+		c.onRotate(*ev)
+	}
+}
@@ -0,0 +1,169 @@
+package form3go
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKey(t *testing.T, id string, notBefore, notAfter time.Time) (Key, *ecdsa.PrivateKey) {
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	signer, err := NewCryptoSigner(pk)
+	assert.Nil(t, err)
+	return Key{ID: id, Signer: signer, NotBefore: notBefore, NotAfter: notAfter}, pk
+}
+
+func TestKeyRing(t *testing.T) {
+	now := time.Now()
+	old, _ := newTestKey(t, "old", now.Add(-2*time.Hour), now.Add(time.Hour))
+	cur, _ := newTestKey(t, "current", now.Add(-time.Hour), time.Time{})
+	next, _ := newTestKey(t, "next", now.Add(time.Hour), time.Time{})
+	ring, err := NewKeyRing(old, next, cur)
+	assert.Nil(t, err)
+
+	// newest active key is current
+	k, ok := ring.Current(now)
+	assert.True(t, ok)
+	assert.Equal(t, "current", k.ID)
+	k, _ = ring.Current(now.Add(2 * time.Hour))
+	assert.Equal(t, "next", k.ID)
+	k, _ = ring.Current(now.Add(-90 * time.Minute))
+	assert.Equal(t, "old", k.ID)
+	_, ok = ring.Current(now.Add(-3 * time.Hour))
+	assert.False(t, ok)
+
+	// previous key must be active
+	k, ok = ring.previous("current", now)
+	assert.True(t, ok)
+	assert.Equal(t, "old", k.ID)
+	_, ok = ring.previous("current", now.Add(90*time.Minute))
+	assert.False(t, ok)
+
+	// rejected key is skipped until its TTL elapses
+	ev := ring.reject("current", "old", now)
+	assert.Equal(t, KeyRotation{From: "current", To: "old", Reason: RotationFallback, At: now}, *ev)
+	k, _ = ring.Current(now)
+	assert.Equal(t, "old", k.ID)
+	k, _ = ring.Current(now.Add(keyRejectTTL))
+	assert.Equal(t, "current", k.ID)
+
+	ring.Remove("old")
+	k, _ = ring.Current(now)
+	assert.Equal(t, "current", k.ID)
+
+	// invalid keys
+	assert.NotNil(t, ring.Add(Key{Signer: cur.Signer}))
+	assert.NotNil(t, ring.Add(Key{ID: "k"}))
+	assert.NotNil(t, ring.Add(Key{ID: "k", Signer: cur.Signer, NotBefore: now, NotAfter: now}))
+	_, err = NewClient(WithBaseURL("http://localhost:8080"), WithKeyRing(nil))
+	assert.NotNil(t, err)
+}
+
+func TestClientKeyRing(t *testing.T) {
+	now := time.Now()
+	old, oldPK := newTestKey(t, "old", now.Add(-time.Hour), time.Time{})
+	cur, curPK := newTestKey(t, "current", now.Add(-time.Minute), time.Time{})
+	ring, err := NewKeyRing(old, cur)
+	assert.Nil(t, err)
+
+	// current key is not registered yet
+	keys := StaticKeyStore{"old": oldPK.Public()}
+	var mu sync.Mutex
+	var keyIDs []string
+	v := &Verifier{Keys: KeyStoreFunc(func(keyID string) (crypto.PublicKey, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		keyIDs = append(keyIDs, keyID)
+		return keys.PublicKey(keyID)
+	})}
+	ts := httptest.NewServer(v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})))
+	defer ts.Close()
+
+	var logs bytes.Buffer
+	var rotations []KeyRotation
+	client, err := NewClient(
+		WithBaseURL(ts.URL),
+		WithKeyRing(ring),
+		WithLogger(log.New(&logs, "", 0)),
+		WithKeyRotationHook(func(ev KeyRotation) { rotations = append(rotations, ev) }),
+	)
+	assert.Nil(t, err)
+
+	// rejected current key falls back to previous one
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"current", "old", "old"}, keyIDs)
+	assert.Len(t, rotations, 1)
+	assert.Equal(t, "current", rotations[0].From)
+	assert.Equal(t, "old", rotations[0].To)
+	assert.Equal(t, RotationFallback, rotations[0].Reason)
+	assert.Contains(t, logs.String(), "form3go: signing key rotated from current to old (fallback)")
+
+	// request body is sent again with previous key
+	ring.Remove("old")
+	next, _ := newTestKey(t, "next", now.Add(-time.Second), time.Time{})
+	assert.Nil(t, ring.Add(next))
+	keys["current"] = curPK.Public()
+	keyIDs = nil
+	account := Account{}
+	assert.Nil(t, json.Unmarshal([]byte(testAccountInfo), &account))
+	_, err = client.CreateAccount(account)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"next", "current"}, keyIDs)
+	assert.Len(t, rotations, 3)
+	assert.Equal(t, KeyRotation{From: "old", To: "next", Reason: RotationActivated, At: rotations[1].At}, rotations[1])
+	assert.Equal(t, KeyRotation{From: "next", To: "current", Reason: RotationFallback, At: rotations[2].At}, rotations[2])
+
+	// both keys rejected
+	delete(keys, "current")
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.True(t, IsUnauthorized(err))
+	assert.Equal(t, 1, strings.Count(logs.String(), "rotated from next to current"))
+}
+
+func TestClientKeyRingActivation(t *testing.T) {
+	now := time.Now()
+	old, oldPK := newTestKey(t, "old", now.Add(-time.Hour), time.Time{})
+	next, nextPK := newTestKey(t, "next", now.Add(50*time.Millisecond), time.Time{})
+	ring, err := NewKeyRing(old, next)
+	assert.Nil(t, err)
+
+	v := &Verifier{Keys: StaticKeyStore{"old": oldPK.Public(), "next": nextPK.Public()}}
+	ts := httptest.NewServer(v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})))
+	defer ts.Close()
+
+	var rotations []KeyRotation
+	client, err := NewClient(WithBaseURL(ts.URL), WithKeyRing(ring),
+		WithKeyRotationHook(func(ev KeyRotation) { rotations = append(rotations, ev) }))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Len(t, rotations, 0)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Len(t, rotations, 1)
+	assert.Equal(t, "old", rotations[0].From)
+	assert.Equal(t, "next", rotations[0].To)
+	assert.Equal(t, RotationActivated, rotations[0].Reason)
+}
@@ -0,0 +1,50 @@
+package form3go
+
+import "net/http"
+
+// Doer does HTTP requests. *http.Client implements it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts function to Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps Doer, e.g. for logging, tracing, metrics, header
+// injection, fault injection or custom auth.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware appends middlewares to client request pipeline.
+// The first given middleware is the outermost one.
+//
+// Requests pass, in order, retries, rate limiter, middlewares given to
+// WithMiddleware, request signing and finally HTTP client. Middlewares
+// therefore see every attempt and may set Authorization header to
+// replace built-in request signing.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) error {
+		c.middlewares = append(c.middlewares, mw...)
+		return nil
+	}
+}
+
+// chain builds client request pipeline.
+func (c *Client) chain() Doer {
+	var d Doer = c.httpClient
+	d = c.signRequests(d)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		d = c.middlewares[i](d)
+	}
+	if c.limiter != nil {
+		d = c.limiter.middleware(d, c.baseURL.Path)
+	}
+	if c.retry.MaxAttempts > 1 {
+		d = retryMiddleware(c.retry)(d)
+	}
+	return d
+}
@@ -0,0 +1,96 @@
+package form3go
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				calls = append(calls, name)
+				mu.Unlock()
+				return next.Do(req)
+			})
+		}
+	}
+
+	attempts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// signing runs after middlewares
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "Signature "))
+		assert.Equal(t, "injected", r.Header.Get("X-Injected"))
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	},
+		WithRetryPolicy(testRetryPolicy),
+		WithMiddleware(record("first"), record("second")),
+		WithMiddleware(func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Injected", "injected")
+				return next.Do(req)
+			})
+		}),
+	)
+
+	_, err := client.ListAccounts(0, 1)
+	assert.Nil(t, err)
+	// every attempt passes middlewares in given order
+	assert.Equal(t, []string{"first", "second", "first", "second"}, calls)
+}
+
+func TestMiddlewareCustomAuth(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("Date"))
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}, WithMiddleware(func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer token")
+			return next.Do(req)
+		})
+	}))
+
+	_, err := client.ListAccounts(0, 1)
+	assert.Nil(t, err)
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	faults := 2
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	},
+		WithRetryPolicy(testRetryPolicy),
+		WithMiddleware(func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				if faults > 0 {
+					faults--
+					return &http.Response{
+						StatusCode: http.StatusServiceUnavailable,
+						Header:     http.Header{},
+						Body:       ioutil.NopCloser(strings.NewReader("")),
+						Request:    req,
+					}, nil
+				}
+				return next.Do(req)
+			})
+		}),
+	)
+
+	_, err := client.ListAccounts(0, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, faults)
+}
@@ -0,0 +1,119 @@
+package form3go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Option configures Client created by NewClient.
+type Option func(*Client) error
+
+// WithBaseURL sets Account API URL including scheme,
+// e.g. "https://api.staging-form3.tech" or "http://localhost:8080".
+func WithBaseURL(rawurl string) Option {
+	return func(c *Client) error {
+		if rawurl == "" {
+			return ErrEmptyBaseURL
+		}
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return ErrInvalidBaseURL
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidBaseURL
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithKeyID sets public key ID used in Authorization header.
+func WithKeyID(id string) Option {
+	return func(c *Client) error {
+		c.keyID = id
+		return nil
+	}
+}
+
+// WithPrivateKeyPath sets path of PEM encoded private key used for
+// signing requests. Key is loaded once by NewClient, see WithKeyReload
+// for picking up rotated keys.
+func WithPrivateKeyPath(path string) Option {
+	return func(c *Client) error {
+		c.keyPath = path
+		return nil
+	}
+}
+
+// WithPrivateKey sets PEM encoded private key used for signing requests.
+// PKCS#1 and PKCS#8 RSA, SEC1 and PKCS#8 ECDSA P-256 and P-384 and PKCS#8
+// Ed25519 keys are supported. Key is parsed by NewClient, encrypted keys
+// require WithPrivateKeyPassphrase.
+func WithPrivateKey(pemBytes []byte) Option {
+	return func(c *Client) error {
+		if len(pemBytes) == 0 {
+			return errors.New("key: no key found")
+		}
+		c.keyPEM = pemBytes
+		return nil
+	}
+}
+
+// WithSigner sets Signer used for signing requests, e.g. SocketSigner
+// for keys held by signing daemon. It takes precedence over
+// WithPrivateKey and WithPrivateKeyPath.
+func WithSigner(s Signer) Option {
+	return func(c *Client) error {
+		c.signer = s
+		return nil
+	}
+}
+
+// WithIdempotentCreate makes CreateAccount safe to repeat, e.g. after
+// timeout when it is unknown whether account was created. When account
+// with the same ID already exists, it is fetched and returned if it has
+// requested attributes, otherwise AccountConflictError is returned.
+// CreateAccount requests are then retried according to retry policy,
+// see WithRetryPolicy.
+func WithIdempotentCreate() Option {
+	return func(c *Client) error {
+		c.idempotentCreate = true
+		return nil
+	}
+}
+
+// WithIDVersions sets UUID versions of account IDs accepted by
+// CreateAccount, DefaultIDVersions by default.
+func WithIDVersions(versions ...int) Option {
+	return func(c *Client) error {
+		for _, v := range versions {
+			if v < 1 || v > 8 {
+				return fmt.Errorf("form3go: invalid UUID version %d", v)
+			}
+		}
+		c.validateOpts.IDVersions = versions
+		return nil
+	}
+}
+
+// WithUserAgent sets User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// WithHTTPClient sets HTTP client used for doing requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		if hc != nil {
+			c.httpClient = hc
+		}
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package form3go
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	// Base URL is required
+	_, err := NewClient()
+	assert.Equal(t, ErrEmptyBaseURL, err)
+	_, err = NewClient(WithBaseURL(""))
+	assert.Equal(t, ErrEmptyBaseURL, err)
+
+	// Base URL without scheme is rejected
+	_, err = NewClient(WithBaseURL("localhost:8080"))
+	assert.Equal(t, ErrInvalidBaseURL, err)
+	_, err = NewClient(WithBaseURL("ftp://localhost:8080"))
+	assert.Equal(t, ErrInvalidBaseURL, err)
+
+	// HTTPS base URL and custom HTTP client
+	hc := &http.Client{}
+	client, err := NewClient(
+		WithBaseURL("https://api.form3.tech/"),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithUserAgent("form3go-test"),
+		WithHTTPClient(hc),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.form3.tech/v1/organisation/accounts", client.endpoint(acctURL))
+	assert.Equal(t, "api.form3.tech", client.baseURL.Host)
+	assert.Equal(t, "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", client.keyID)
+	assert.Equal(t, "form3go-test", client.userAgent)
+	assert.Equal(t, hc, client.httpClient)
+
+	// Private key is parsed when client is created
+	pemBytes, err := ioutil.ReadFile("../test_private_key.pem")
+	assert.Nil(t, err)
+	client, err = NewClient(WithBaseURL("http://localhost:8080"), WithPrivateKey(pemBytes))
+	assert.Nil(t, err)
+	assert.NotNil(t, client.signer)
+	_, err = NewClient(WithBaseURL("http://localhost:8080"), WithPrivateKey([]byte("invalid")))
+	assert.NotNil(t, err)
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	for k, v := range map[string]string{
+		"FORM3_HOST":          "localhost:8080",
+		"FORM3_KEY_ID":        "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8",
+		"FORM3_PRIV_KEY_PATH": "../test_private_key.pem",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	client, err := NewClientFromEnv(WithUserAgent("form3go-test"))
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/v1/organisation/accounts", client.endpoint(acctURL))
+	assert.Equal(t, "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", client.keyID)
+	assert.Equal(t, "../test_private_key.pem", client.keyPath)
+	assert.Equal(t, "form3go-test", client.userAgent)
+
+	// FORM3_HOST may include scheme
+	os.Setenv("FORM3_HOST", "https://api.form3.tech")
+	client, err = NewClientFromEnv()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.form3.tech/v1/organisation/accounts", client.endpoint(acctURL))
+
+	// FORM3_HOST env variable is required
+	os.Setenv("FORM3_HOST", "")
+	_, err = NewClientFromEnv()
+	assert.Equal(t, ErrEmptyHost, err)
+}
+
+func TestWithIDVersions(t *testing.T) {
+	account := newBulkAccounts(t, 1)[0]
+	account.AccountData.ID = "9127e265-9605-1b4b-a0e5-3003ea9cc4dc"
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(testAccountInfo))
+	})
+	_, err := client.CreateAccount(account)
+	assert.True(t, errors.Is(err, ErrInvalidAccount))
+	assert.Contains(t, err.Error(), `UUID "9127e265-9605-1b4b-a0e5-3003ea9cc4dc" is version 1, accepted versions are 4, 5, 7`)
+
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(testAccountInfo))
+	}, WithIDVersions(1, 4))
+	_, err = client.CreateAccount(account)
+	assert.Nil(t, err)
+
+	_, err = NewClient(WithBaseURL("http://localhost"), WithIDVersions(9))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,278 @@
+package form3go
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit configures client side rate limiting shared by all requests
+// of a client.
+type RateLimit struct {
+	// Rate is number of requests allowed per second. Zero disables token
+	// bucket limiting.
+	Rate float64
+	// Burst is number of requests allowed at once, defaults to 1.
+	Burst int
+	// MaxInFlight caps number of concurrent requests. Zero means no cap.
+	MaxInFlight int
+	// Adaptive halves Rate whenever 429 response is observed and
+	// recovers it gradually with successful responses.
+	Adaptive bool
+	// Endpoints overrides limits of requests matching key, given as
+	// method and path prefix, e.g. "POST /v1/organisation/accounts".
+	// Paths are relative to base URL, so its own path is not part of the
+	// key. The longest matching prefix wins and its limits replace
+	// client-wide ones.
+	Endpoints map[string]RateLimit
+}
+
+// RateLimitStats reports client side rate limiting metrics.
+type RateLimitStats struct {
+	// Requests is number of requests which passed the limiter.
+	Requests int64
+	// Waited is number of requests which had to wait.
+	Waited int64
+	// WaitTime is total time spent waiting.
+	WaitTime time.Duration
+	// Throttled is number of 429 responses observed.
+	Throttled int64
+}
+
+// WithRateLimit enables client side rate limiting.
+func WithRateLimit(rl RateLimit) Option {
+	return func(c *Client) error {
+		c.limiter = newRateLimiter(rl)
+		return nil
+	}
+}
+
+// RateLimitStats returns rate limiting metrics of client.
+func (c *Client) RateLimitStats() RateLimitStats {
+	if c.limiter == nil {
+		return RateLimitStats{}
+	}
+	return RateLimitStats{
+		Requests:  atomic.LoadInt64(&c.limiter.requests),
+		Waited:    atomic.LoadInt64(&c.limiter.waited),
+		WaitTime:  time.Duration(atomic.LoadInt64(&c.limiter.waitTime)),
+		Throttled: atomic.LoadInt64(&c.limiter.throttled),
+	}
+}
+
+// rateLimiter applies RateLimit to requests.
+type rateLimiter struct {
+	def       *limit
+	endpoints []endpointLimit
+
+	requests  int64
+	waited    int64
+	waitTime  int64
+	throttled int64
+}
+
+// endpointLimit is limit of requests matching method and path prefix.
+type endpointLimit struct {
+	method string
+	prefix string
+	limit  *limit
+}
+
+// limit is token bucket and in flight semaphore of RateLimit.
+type limit struct {
+	bucket *tokenBucket
+	sem    chan struct{}
+}
+
+func newRateLimiter(rl RateLimit) *rateLimiter {
+	l := &rateLimiter{def: newLimit(rl)}
+	for key, erl := range rl.Endpoints {
+		method, prefix := "", key
+		if i := strings.IndexByte(key, ' '); i >= 0 {
+			method, prefix = key[:i], key[i+1:]
+		}
+		l.endpoints = append(l.endpoints, endpointLimit{method: method, prefix: prefix, limit: newLimit(erl)})
+	}
+	sort.Slice(l.endpoints, func(i, j int) bool {
+		return len(l.endpoints[i].prefix) > len(l.endpoints[j].prefix)
+	})
+	return l
+}
+
+func newLimit(rl RateLimit) *limit {
+	lim := &limit{}
+	if rl.Rate > 0 {
+		lim.bucket = newTokenBucket(rl.Rate, rl.Burst, rl.Adaptive)
+	}
+	if rl.MaxInFlight > 0 {
+		lim.sem = make(chan struct{}, rl.MaxInFlight)
+	}
+	return lim
+}
+
+// limitFor returns limit applied to request with method and path.
+func (l *rateLimiter) limitFor(method, path string) *limit {
+	for _, e := range l.endpoints {
+		if (e.method == "" || e.method == method) && strings.HasPrefix(path, e.prefix) {
+			return e.limit
+		}
+	}
+	return l.def
+}
+
+// middleware applies rate limits to requests passing it. Endpoint limits
+// are matched against request path with basePath, the path of client
+// base URL, stripped.
+func (l *rateLimiter) middleware(next Doer, basePath string) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		path := strings.TrimPrefix(req.URL.Path, basePath)
+		release, err := l.acquire(req.Context(), req.Method, path)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next.Do(req)
+		release()
+		l.observe(req.Method, path, resp)
+		return resp, err
+	})
+}
+
+// acquire waits until request with method and path is allowed. Returned
+// release func must be called once response is received.
+func (l *rateLimiter) acquire(ctx context.Context, method, path string) (func(), error) {
+	lim := l.limitFor(method, path)
+	start := time.Now()
+	waited := false
+
+	if lim.sem != nil {
+		select {
+		case lim.sem <- struct{}{}:
+		default:
+			waited = true
+			select {
+			case lim.sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctxError(ctx, nil)
+			}
+		}
+	}
+	if lim.bucket != nil {
+		w, err := lim.bucket.wait(ctx)
+		if err != nil {
+			if lim.sem != nil {
+				<-lim.sem
+			}
+			return nil, err
+		}
+		waited = waited || w
+	}
+
+	atomic.AddInt64(&l.requests, 1)
+	if waited {
+		atomic.AddInt64(&l.waited, 1)
+		atomic.AddInt64(&l.waitTime, int64(time.Since(start)))
+	}
+	return func() {
+		if lim.sem != nil {
+			<-lim.sem
+		}
+	}, nil
+}
+
+// observe adapts limit of request with method and path to resp.
+func (l *rateLimiter) observe(method, path string, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	throttled := resp.StatusCode == http.StatusTooManyRequests
+	if throttled {
+		atomic.AddInt64(&l.throttled, 1)
+	}
+	if b := l.limitFor(method, path).bucket; b != nil && b.adaptive {
+		b.adapt(throttled)
+	}
+}
+
+// minRateFraction is lowest fraction of configured rate adaptive bucket
+// slows down to.
+const minRateFraction = 1.0 / 64
+
+// tokenBucket is token bucket rate limiter.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	maxRate  float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	adaptive bool
+}
+
+func newTokenBucket(rate float64, burst int, adaptive bool) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:     rate,
+		maxRate:  rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+		adaptive: adaptive,
+	}
+}
+
+// wait reserves token and waits until it is available. It reports
+// whether it had to wait.
+func (b *tokenBucket) wait(ctx context.Context) (bool, error) {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return false, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		// give reserved token back
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return true, ctxError(ctx, nil)
+	}
+}
+
+// adapt halves rate when throttled and increases it additively otherwise.
+func (b *tokenBucket) adapt(throttled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if throttled {
+		b.rate /= 2
+		if min := b.maxRate * minRateFraction; b.rate < min {
+			b.rate = min
+		}
+		return
+	}
+	b.rate += b.maxRate / 100
+	if b.rate > b.maxRate {
+		b.rate = b.maxRate
+	}
+}
@@ -0,0 +1,157 @@
+package form3go
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}, WithRateLimit(RateLimit{Rate: 100, Burst: 2}))
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, err := client.ListAccounts(0, 1)
+		assert.Nil(t, err)
+	}
+	// 2 requests pass at once, following 4 are spaced by 10ms
+	assert.True(t, time.Since(start) >= 35*time.Millisecond)
+
+	stats := client.RateLimitStats()
+	assert.Equal(t, int64(6), stats.Requests)
+	assert.Equal(t, int64(4), stats.Waited)
+	assert.True(t, stats.WaitTime > 0 && stats.WaitTime <= time.Since(start))
+}
+
+func TestRateLimitMaxInFlight(t *testing.T) {
+	var inFlight, maxInFlight int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}, WithRateLimit(RateLimit{MaxInFlight: 2}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.ListAccounts(0, 1)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), maxInFlight)
+	assert.Equal(t, int64(10), client.RateLimitStats().Requests)
+}
+
+func TestRateLimitEndpoints(t *testing.T) {
+	l := newRateLimiter(RateLimit{
+		Rate: 10,
+		Endpoints: map[string]RateLimit{
+			"POST /v1/organisation/accounts": {Rate: 1},
+			"/v1/organisation/accounts/":     {Rate: 5},
+		},
+	})
+	assert.Equal(t, float64(1), l.limitFor("POST", "/v1/organisation/accounts").bucket.rate)
+	assert.Equal(t, float64(5), l.limitFor("GET", "/v1/organisation/accounts/9127e265-9605-4b4b-a0e5-3003ea9cc4dc").bucket.rate)
+	assert.Equal(t, float64(10), l.limitFor("GET", "/v1/organisation/accounts").bucket.rate)
+}
+
+func TestRateLimitEndpointsBasePath(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	client, err := NewClient(
+		WithBaseURL(srv.URL+"/prefix"),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithPrivateKeyPath("../test_private_key.pem"),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithRateLimit(RateLimit{
+			Rate: 1000,
+			Endpoints: map[string]RateLimit{
+				"GET /v1/organisation/accounts": {Rate: 1000, Adaptive: true},
+			},
+		}),
+	)
+	assert.Nil(t, err)
+
+	_, err = client.ListAccounts(0, 1)
+	assert.True(t, IsRateLimited(err))
+	assert.Equal(t, []string{"/prefix/v1/organisation/accounts"}, paths)
+	// throttled request is attributed to endpoint limit
+	assert.Equal(t, float64(500), client.limiter.endpoints[0].limit.bucket.rate)
+	assert.Equal(t, float64(1000), client.limiter.def.bucket.rate)
+}
+
+func TestRateLimitContext(t *testing.T) {
+	l := newRateLimiter(RateLimit{Rate: 1, MaxInFlight: 1})
+	release, err := l.acquire(context.Background(), "GET", acctURL)
+	assert.Nil(t, err)
+
+	// In flight slot is taken
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, "GET", acctURL)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	release()
+
+	// Token is not available yet
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, "GET", acctURL)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 0, len(l.def.sem))
+}
+
+func TestRateLimitAdaptive(t *testing.T) {
+	var throttle int32 = 1
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&throttle) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}, WithRateLimit(RateLimit{Rate: 1000, Burst: 10, Adaptive: true}))
+	bucket := client.limiter.def.bucket
+
+	_, err := client.ListAccounts(0, 1)
+	assert.True(t, IsRateLimited(err))
+	_, _ = client.ListAccounts(0, 1)
+	assert.Equal(t, float64(250), bucket.rate)
+	assert.Equal(t, int64(2), client.RateLimitStats().Throttled)
+
+	// Rate recovers with successful responses
+	atomic.StoreInt32(&throttle, 0)
+	_, err = client.ListAccounts(0, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(260), bucket.rate)
+
+	for i := 0; i < 200; i++ {
+		bucket.adapt(true)
+	}
+	assert.Equal(t, 1000*minRateFraction, bucket.rate)
+	for i := 0; i < 200; i++ {
+		bucket.adapt(false)
+	}
+	assert.Equal(t, float64(1000), bucket.rate)
+}
@@ -0,0 +1,103 @@
+package form3go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// idempotentKey is context key marking requests which are safe to retry
+// regardless of their method.
+type idempotentKey struct{}
+
+// withIdempotent returns ctx marking request as safe to retry, e.g.
+// POST whose repetition is detected and resolved by caller.
+func withIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent reports whether ctx marks request as safe to retry.
+func isIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
+
+// do executes request to Account API path with query. body is encoded as
+// JSON when it is not nil, body of successful response is decoded into
+// out when it is not nil. Non-2xx responses are returned as APIError
+// matching opErr. Every request passes client request pipeline so it is
+// retried, rate limited and signed.
+func (c *Client) do(ctx context.Context, opErr error, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	// do request
+	resp, err := c.doer.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+		}
+		return ctxError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	// check response
+	return handleResponse(ctx, resp, opErr, out)
+}
+
+// newRequest creates HTTP request. Request is signed by signing
+// middleware of client request pipeline.
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	reqURL := c.endpoint(path)
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var data []byte
+	var reqBody io.Reader
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("form3go: unexpected JSON marshal failure: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("form3go: unexpected HTTP request failure: %v", err)
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/vnd.api+json")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/vnd.api+json")
+		req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	}
+	return req, nil
+}
+
+// handleResponse checks response status and decodes body of successful
+// response into out. Non-2xx responses are returned as APIError matching
+// opErr. out may be nil for responses without body.
+func handleResponse(ctx context.Context, resp *http.Response, opErr error, out interface{}) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp, opErr)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return ctxError(ctx, fmt.Errorf("form3go: unexpected response decode failure: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package form3go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	errOp := errors.New("form3go: test operation failure")
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/v1/test/resources":
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "a b&c", r.URL.Query().Get("filter[name]"))
+			assert.Equal(t, "application/vnd.api+json", r.Header.Get("Content-Type"))
+			assert.NotEmpty(t, r.Header.Get("Digest"))
+			assert.NotEmpty(t, r.Header.Get("Authorization"))
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, `{"name":"resource"}`, string(body))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		case "/v1/test/resources/a%2Fb":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	})
+
+	// Body is encoded and response is decoded
+	query := url.Values{}
+	query.Set("filter[name]", "a b&c")
+	out := struct {
+		ID string `json:"id"`
+	}{}
+	err := client.do(context.Background(), errOp, "POST", "/v1/test/resources", query, map[string]string{"name": "resource"}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", out.ID)
+
+	// Non-2xx response matches operation error
+	err = client.do(context.Background(), errOp, "GET", "/v1/test/resources/"+url.PathEscape("a/b"), nil, nil, &out)
+	assert.True(t, errors.Is(err, errOp))
+	assert.True(t, IsNotFound(err))
+
+	// Unencodable body
+	err = client.do(context.Background(), errOp, "POST", "/v1/test/resources", nil, func() {}, nil)
+	assert.Contains(t, err.Error(), "form3go: unexpected JSON marshal failure")
+}
+
+func TestNewRequestHeaders(t *testing.T) {
+	client, err := NewClient(WithBaseURL("http://localhost:8080"))
+	assert.Nil(t, err)
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+
+	req, err := client.newRequest(context.Background(), "POST", acctURL, nil, *account)
+	assert.Nil(t, err)
+	assert.Equal(t, "form3go", req.Header.Get("User-Agent"))
+	assert.Equal(t, "application/vnd.api+json", req.Header.Get("Content-Type"))
+	// Account API does not support idempotency keys
+	assert.Empty(t, req.Header.Get("Idempotency-Key"))
+}
@@ -0,0 +1,184 @@
+package form3go
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures retries of transient failures. Every attempt
+// is signed again with fresh Date header.
+type RetryPolicy struct {
+	// MaxAttempts is maximum number of attempts including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is delay before the first retry, it doubles with every
+	// following retry.
+	BaseDelay time.Duration
+	// MaxDelay caps computed delay. Retry-After header of response
+	// is honoured even if it is longer.
+	MaxDelay time.Duration
+	// Jitter is fraction of computed delay, between 0 and 1, which is
+	// randomly subtracted from it.
+	Jitter float64
+	// StatusCodes are response status codes which are retried.
+	StatusCodes []int
+	// Methods are HTTP methods which are retried. Other methods, e.g.
+	// non-idempotent POST, are not retried, except CreateAccount of
+	// client with WithIdempotentCreate, which resolves conflict of
+	// account created by earlier attempt.
+	Methods []string
+}
+
+// DefaultRetryPolicy retries rate limited and unavailable responses as
+// well as connection failures up to 4 attempts.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+	StatusCodes: []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+	Methods: []string{"GET", "HEAD", "DELETE"},
+}
+
+// WithRetryPolicy sets policy for retrying transient failures.
+// Requests are not retried by default.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) error {
+		c.retry = p
+		return nil
+	}
+}
+
+// retryMiddleware retries requests according to policy p. Every
+// attempt passes the rest of request pipeline, including signing.
+func retryMiddleware(p RetryPolicy) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			idempotent := isIdempotent(ctx)
+			for attempt := 1; ; attempt++ {
+				attemptReq := req
+				if attempt > 1 {
+					attemptReq = req.Clone(ctx)
+					if req.GetBody != nil {
+						body, err := req.GetBody()
+						if err != nil {
+							return nil, err
+						}
+						attemptReq.Body = body
+					}
+				}
+				resp, err := next.Do(attemptReq)
+				if err != nil && ctx.Err() != nil {
+					return nil, err
+				}
+				if attempt >= p.MaxAttempts || !p.retryable(req.Method, idempotent, resp, err) {
+					return resp, err
+				}
+
+				delay := p.backoff(attempt, resp)
+				if resp != nil {
+					_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+					resp.Body.Close()
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctxError(ctx, nil)
+				case <-timer.C:
+				}
+			}
+		})
+	}
+}
+
+// retryable reports whether request with method failed with resp or err
+// can be retried.
+func (p RetryPolicy) retryable(method string, idempotent bool, resp *http.Response, err error) bool {
+	if !idempotent && !containsString(p.Methods, method) {
+		return false
+	}
+	if err != nil {
+		return isTransientError(err)
+	}
+	for _, code := range p.StatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns delay before next attempt after given attempt failed
+// with resp, which may be nil.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return d
+		}
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// parseRetryAfter parses Retry-After header given either in seconds or
+// as HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// isTransientError reports whether err is connection failure worth
+// retrying.
+func isTransientError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
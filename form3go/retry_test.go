@@ -0,0 +1,189 @@
+package form3go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+	Jitter:      0.5,
+	StatusCodes: DefaultRetryPolicy.StatusCodes,
+	Methods:     DefaultRetryPolicy.Methods,
+}
+
+func TestRetry(t *testing.T) {
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+
+	tests := []struct {
+		name     string
+		statuses []int
+		call     func(c *Client) error
+		opts     []Option
+		attempts int32
+		err      bool
+	}{
+		{
+			name:     "GET succeeds after transient failures",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			call:     func(c *Client) error { _, err := c.FetchAccount(account.AccountData.ID); return err },
+			attempts: 3,
+		},
+		{
+			name:     "GET gives up after max attempts",
+			statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			call:     func(c *Client) error { _, err := c.FetchAccount(account.AccountData.ID); return err },
+			attempts: 3,
+			err:      true,
+		},
+		{
+			name:     "non transient status is not retried",
+			statuses: []int{http.StatusInternalServerError, http.StatusOK},
+			call:     func(c *Client) error { _, err := c.ListAccounts(0, 1); return err },
+			attempts: 1,
+			err:      true,
+		},
+		{
+			name:     "DELETE is retried",
+			statuses: []int{http.StatusGatewayTimeout, http.StatusNoContent},
+			call:     func(c *Client) error { return c.DeleteAccount(account.AccountData.ID, 0) },
+			attempts: 2,
+		},
+		{
+			name:     "POST is not retried",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusCreated},
+			call:     func(c *Client) error { _, err := c.CreateAccount(*account); return err },
+			attempts: 1,
+			err:      true,
+		},
+		{
+			name:     "idempotent POST is retried",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusCreated},
+			call:     func(c *Client) error { _, err := c.CreateAccount(*account); return err },
+			opts:     []Option{WithIdempotentCreate()},
+			attempts: 2,
+		},
+		{
+			name:     "idempotent POST created by failed attempt",
+			statuses: []int{http.StatusBadGateway, http.StatusConflict, http.StatusOK},
+			call:     func(c *Client) error { _, err := c.CreateAccount(*account); return err },
+			opts:     []Option{WithIdempotentCreate()},
+			attempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		var attempts int32
+		var dates []string
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&attempts, 1)
+			dates = append(dates, r.Header.Get("Date"))
+			assert.NotEmpty(t, r.Header.Get("Authorization"), tt.name)
+			w.WriteHeader(tt.statuses[n-1])
+			if r.Method != "DELETE" {
+				_, _ = w.Write([]byte(testAccountInfo))
+			}
+		}, append(tt.opts, WithRetryPolicy(testRetryPolicy))...)
+
+		err := tt.call(client)
+		assert.Equal(t, tt.err, err != nil, tt.name)
+		assert.Equal(t, tt.attempts, attempts, tt.name)
+		assert.Len(t, dates, int(tt.attempts), tt.name)
+	}
+}
+
+func TestRetryPost(t *testing.T) {
+	var attempts int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithRetryPolicy(testRetryPolicy))
+
+	err := client.do(context.Background(), nil, "POST", acctURL, nil, struct{}{}, nil)
+	assert.True(t, IsServerError(err))
+	assert.Equal(t, int32(1), attempts)
+}
+
+func TestRetryConnectionFailure(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// drop connection without response
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		_, _ = w.Write([]byte(testAccountInfo))
+	}))
+	defer srv.Close()
+	client, err := NewClient(
+		WithBaseURL(srv.URL),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithPrivateKeyPath("../test_private_key.pem"),
+		WithRetryPolicy(testRetryPolicy),
+	)
+	assert.Nil(t, err)
+
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), attempts)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1, nil))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2, nil))
+	assert.Equal(t, 800*time.Millisecond, p.backoff(4, nil))
+	assert.Equal(t, time.Second, p.backoff(5, nil))
+
+	// Jitter reduces delay by at most given fraction
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2, nil)
+		assert.True(t, d > 100*time.Millisecond && d <= 200*time.Millisecond)
+	}
+
+	// Retry-After takes precedence over computed delay
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	assert.Equal(t, 3*time.Second, p.backoff(1, resp))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 8, 8, 52, 44, 0, time.UTC)
+
+	d, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = parseRetryAfter("Wed, 08 Jan 2020 08:53:14 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	d, ok = parseRetryAfter("Wed, 08 Jan 2020 08:50:00 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	for _, v := range []string{"", "-1", "soon"} {
+		_, ok = parseRetryAfter(v, now)
+		assert.False(t, ok, v)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	assert.True(t, isTransientError(&net.OpError{Op: "read", Err: syscall.ECONNRESET}))
+	assert.True(t, isTransientError(io.ErrUnexpectedEOF))
+	assert.False(t, isTransientError(errors.New("x509: certificate signed by unknown authority")))
+}
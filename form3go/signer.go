@@ -0,0 +1,335 @@
+package form3go
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/youmark/pkcs8"
+)
+
+// Signature algorithms declared in Authorization header.
+const (
+	AlgorithmRSASHA256   = "rsa-sha256"
+	AlgorithmECDSASHA256 = "ecdsa-sha256"
+	AlgorithmECDSASHA384 = "ecdsa-sha384"
+	AlgorithmEd25519     = "ed25519"
+)
+
+// Signer signs request signature string.
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+	// Algorithm returns signature algorithm declared in Authorization
+	// header, e.g. AlgorithmRSASHA256.
+	Algorithm() string
+}
+
+// PassphraseFunc returns passphrase of encrypted PEM private key.
+type PassphraseFunc func() ([]byte, error)
+
+// WithPrivateKeyPassphrase sets passphrase callback used for decrypting
+// encrypted PEM private keys given to WithPrivateKey and
+// WithPrivateKeyPath. Both encrypted PKCS#8 ("ENCRYPTED PRIVATE KEY",
+// PBES2 as written by openssl by default) and legacy RFC 1423 PEM
+// encryption are supported.
+func WithPrivateKeyPassphrase(fn PassphraseFunc) Option {
+	return func(c *Client) error {
+		c.passphrase = fn
+		return nil
+	}
+}
+
+// WithKeyReload makes client check private key file given to
+// WithPrivateKeyPath for changes at most once per interval and reload it
+// when its modification time or size changes, so rotated keys are picked
+// up without restarting. If reloaded key cannot be parsed, e.g. while it
+// is being written, previous key keeps being used.
+func WithKeyReload(interval time.Duration) Option {
+	return func(c *Client) error {
+		if interval <= 0 {
+			return errors.New("form3go: key reload interval must be positive")
+		}
+		c.keyReload = interval
+		return nil
+	}
+}
+
+// keyFile caches Signer of private key file, reloading it on change when
+// interval is set. Checks are done by signing requests, there is no
+// background goroutine.
+type keyFile struct {
+	path       string
+	passphrase PassphraseFunc
+	interval   time.Duration
+
+	mu      sync.Mutex
+	signer  Signer
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+func newKeyFile(path string, passphrase PassphraseFunc, interval time.Duration) (*keyFile, error) {
+	k := &keyFile{path: path, passphrase: passphrase, interval: interval}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.load(fi); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// load parses key file described by fi.
+func (k *keyFile) load(fi os.FileInfo) error {
+	signer, err := loadPrivateKey(k.path, k.passphrase)
+	if err != nil {
+		return err
+	}
+	k.signer = signer
+	k.modTime = fi.ModTime()
+	k.size = fi.Size()
+	k.checked = time.Now()
+	return nil
+}
+
+// current returns Signer of current key, reloading key file first if it
+// changed since last check.
+func (k *keyFile) current() Signer {
+	if k.interval == 0 {
+		return k.signer
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if time.Since(k.checked) < k.interval {
+		return k.signer
+	}
+	k.checked = time.Now()
+	fi, err := os.Stat(k.path)
+	if err != nil || (fi.ModTime().Equal(k.modTime) && fi.Size() == k.size) {
+		return k.signer
+	}
+	_ = k.load(fi)
+	return k.signer
+}
+
+func loadPrivateKey(path string, passphrase PassphraseFunc) (Signer, error) {
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKey(dat, passphrase)
+}
+
+// parsePrivateKey parses PKCS#1 RSA, SEC1 EC or PKCS#8 RSA, ECDSA and
+// Ed25519 PEM private keys. Legacy encrypted PEM blocks are decrypted
+// with passphrase.
+func parsePrivateKey(pemBytes []byte, passphrase PassphraseFunc) (Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("key: no key found")
+	}
+
+	// password returns passphrase of encrypted key
+	password := func() ([]byte, error) {
+		if passphrase == nil {
+			return nil, errors.New("key: encrypted key requires passphrase")
+		}
+		pass, err := passphrase()
+		if err != nil {
+			return nil, fmt.Errorf("key: passphrase: %v", err)
+		}
+		return pass, nil
+	}
+
+	der := block.Bytes
+	//lint:ignore SA1019 legacy encrypted PEM is still produced by openssl
+	if x509.IsEncryptedPEMBlock(block) {
+		pass, err := password()
+		if err != nil {
+			return nil, err
+		}
+		//lint:ignore SA1019 legacy encrypted PEM is still produced by openssl
+		der, err = x509.DecryptPEMBlock(block, pass)
+		if err != nil {
+			return nil, fmt.Errorf("key: %v", err)
+		}
+	}
+
+	var rawkey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		rawkey, err = x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		rawkey, err = x509.ParseECPrivateKey(der)
+	case "PRIVATE KEY":
+		rawkey, err = x509.ParsePKCS8PrivateKey(der)
+	case "ENCRYPTED PRIVATE KEY":
+		// x509 does not decrypt PKCS#8 keys
+		var pass []byte
+		if pass, err = password(); err != nil {
+			return nil, err
+		}
+		if len(pass) == 0 {
+			return nil, errors.New("key: encrypted key requires passphrase")
+		}
+		if rawkey, err = pkcs8.ParsePKCS8PrivateKey(der, pass); err != nil {
+			return nil, fmt.Errorf("key: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("key: unsupported key type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSignerFromKey(rawkey)
+}
+
+func newSignerFromKey(k interface{}) (Signer, error) {
+	switch t := k.(type) {
+	case *rsa.PrivateKey:
+		return &rsaPrivateKey{t}, nil
+	case *ecdsa.PrivateKey:
+		switch t.Curve {
+		case elliptic.P256():
+			return &ecdsaPrivateKey{t, crypto.SHA256, AlgorithmECDSASHA256}, nil
+		case elliptic.P384():
+			return &ecdsaPrivateKey{t, crypto.SHA384, AlgorithmECDSASHA384}, nil
+		}
+		return nil, fmt.Errorf("key: unsupported curve %s", t.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return ed25519PrivateKey(t), nil
+	case *ed25519.PrivateKey:
+		return ed25519PrivateKey(*t), nil
+	default:
+		return nil, fmt.Errorf("key: unsupported key type %T", k)
+	}
+}
+
+type rsaPrivateKey struct {
+	*rsa.PrivateKey
+}
+
+func (r *rsaPrivateKey) Sign(data []byte) ([]byte, error) {
+	h := sha256.New()
+	h.Write(data)
+	d := h.Sum(nil)
+	return rsa.SignPKCS1v15(rand.Reader, r.PrivateKey, crypto.SHA256, d)
+}
+
+func (r *rsaPrivateKey) Algorithm() string {
+	return AlgorithmRSASHA256
+}
+
+type ecdsaPrivateKey struct {
+	*ecdsa.PrivateKey
+	hash      crypto.Hash
+	algorithm string
+}
+
+// Sign returns ASN.1 DER encoded ECDSA signature of data.
+func (e *ecdsaPrivateKey) Sign(data []byte) ([]byte, error) {
+	return ecdsa.SignASN1(rand.Reader, e.PrivateKey, hashData(e.hash, data))
+}
+
+func (e *ecdsaPrivateKey) Algorithm() string {
+	return e.algorithm
+}
+
+type ed25519PrivateKey ed25519.PrivateKey
+
+func (e ed25519PrivateKey) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(e), data), nil
+}
+
+func (e ed25519PrivateKey) Algorithm() string {
+	return AlgorithmEd25519
+}
+
+// hashData returns digest of data computed with SHA-256 or SHA-384.
+func hashData(hash crypto.Hash, data []byte) []byte {
+	if hash == crypto.SHA384 {
+		d := sha512.Sum384(data)
+		return d[:]
+	}
+	d := sha256.Sum256(data)
+	return d[:]
+}
+
+// WithCryptoSigner sets crypto.Signer used for signing requests, e.g.
+// key held by PKCS#11 token or cloud KMS. See NewCryptoSigner.
+func WithCryptoSigner(s crypto.Signer) Option {
+	return func(c *Client) error {
+		signer, err := NewCryptoSigner(s)
+		if err != nil {
+			return err
+		}
+		c.signer = signer
+		return nil
+	}
+}
+
+// NewCryptoSigner adapts crypto.Signer to Signer. Signature algorithm is
+// derived from public key: RSA keys sign PKCS#1 v1.5 SHA-256 digest,
+// ECDSA P-256 and P-384 keys sign SHA-256 and SHA-384 digest and must
+// return ASN.1 DER signature, Ed25519 keys sign the data itself.
+func NewCryptoSigner(s crypto.Signer) (Signer, error) {
+	switch k := s.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return newSignerFromKey(k)
+	}
+
+	cs := &cryptoSigner{signer: s}
+	switch pub := s.Public().(type) {
+	case *rsa.PublicKey:
+		cs.hash, cs.algorithm = crypto.SHA256, AlgorithmRSASHA256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			cs.hash, cs.algorithm = crypto.SHA256, AlgorithmECDSASHA256
+		case elliptic.P384():
+			cs.hash, cs.algorithm = crypto.SHA384, AlgorithmECDSASHA384
+		default:
+			return nil, fmt.Errorf("key: unsupported curve %s", pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		cs.algorithm = AlgorithmEd25519
+	default:
+		return nil, fmt.Errorf("key: unsupported public key type %T", pub)
+	}
+	return cs, nil
+}
+
+// cryptoSigner is Signer backed by crypto.Signer.
+type cryptoSigner struct {
+	signer    crypto.Signer
+	hash      crypto.Hash
+	algorithm string
+}
+
+func (s *cryptoSigner) Sign(data []byte) ([]byte, error) {
+	if s.hash == 0 {
+		return s.signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	return s.signer.Sign(rand.Reader, hashData(s.hash, data), s.hash)
+}
+
+func (s *cryptoSigner) Algorithm() string {
+	return s.algorithm
+}
@@ -0,0 +1,94 @@
+package form3go
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultSocketSignerTimeout is timeout of SocketSigner signing when
+// Timeout is not provided.
+const DefaultSocketSignerTimeout = 5 * time.Second
+
+// SocketSigner is Signer delegating signing to local signing daemon, e.g.
+// HSM or KMS agent, listening on Unix socket.
+//
+// Every signature is requested over new connection with single JSON line
+//
+//	{"key":"<Key>","algorithm":"<SignatureAlgorithm>","data":"<base64>"}
+//
+// and daemon replies with single JSON line
+//
+//	{"signature":"<base64>"} or {"error":"<message>"}
+//
+// Signature must be in format of SignatureAlgorithm, i.e. PKCS#1 v1.5 for
+// rsa-sha256 and ASN.1 DER for ecdsa algorithms.
+type SocketSigner struct {
+	// Path is path of daemon Unix socket.
+	Path string
+	// Key is name of key held by daemon.
+	Key string
+	// SignatureAlgorithm is signature algorithm of key, e.g.
+	// AlgorithmRSASHA256.
+	SignatureAlgorithm string
+	// Timeout bounds single signing, DefaultSocketSignerTimeout is used
+	// when it is zero.
+	Timeout time.Duration
+}
+
+// socketSignRequest is signing request sent to daemon.
+type socketSignRequest struct {
+	Key       string `json:"key"`
+	Algorithm string `json:"algorithm"`
+	Data      []byte `json:"data"`
+}
+
+// socketSignResponse is daemon response to socketSignRequest.
+type socketSignResponse struct {
+	Signature []byte `json:"signature"`
+	Error     string `json:"error"`
+}
+
+// Sign requests signature of data from daemon.
+func (s *SocketSigner) Sign(data []byte) ([]byte, error) {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultSocketSignerTimeout
+	}
+	conn, err := net.DialTimeout("unix", s.Path, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("socket signer: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("socket signer: %v", err)
+	}
+
+	req := socketSignRequest{Key: s.Key, Algorithm: s.SignatureAlgorithm, Data: data}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("socket signer: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("socket signer: %v", err)
+	}
+	var resp socketSignResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("socket signer: malformed response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("socket signer: %s", resp.Error)
+	}
+	if len(resp.Signature) == 0 {
+		return nil, errors.New("socket signer: empty signature")
+	}
+	return resp.Signature, nil
+}
+
+// Algorithm returns SignatureAlgorithm.
+func (s *SocketSigner) Algorithm() string {
+	return s.SignatureAlgorithm
+}
@@ -0,0 +1,158 @@
+package form3go
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSigningDaemon serves SocketSigner protocol on Unix socket signing
+// with keys held in memory.
+type fakeSigningDaemon struct {
+	path string
+	keys map[string]Signer
+	ln   net.Listener
+}
+
+func newFakeSigningDaemon(t *testing.T, keys map[string]Signer) *fakeSigningDaemon {
+	dir, err := ioutil.TempDir("", "form3go")
+	assert.Nil(t, err)
+	d := &fakeSigningDaemon{path: filepath.Join(dir, "signer.sock"), keys: keys}
+	d.ln, err = net.Listen("unix", d.path)
+	assert.Nil(t, err)
+	go d.serve()
+	t.Cleanup(func() {
+		d.ln.Close()
+		os.RemoveAll(dir)
+	})
+	return d
+}
+
+func (d *fakeSigningDaemon) serve() {
+	for {
+		conn, err := d.ln.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *fakeSigningDaemon) handle(conn net.Conn) {
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var req socketSignRequest
+	var resp socketSignResponse
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = "malformed request"
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+	key, ok := d.keys[req.Key]
+	switch {
+	case !ok:
+		resp.Error = "unknown key " + req.Key
+	case key.Algorithm() != req.Algorithm:
+		resp.Error = "key " + req.Key + " does not support " + req.Algorithm
+	default:
+		resp.Signature, err = key.Sign(req.Data)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+func TestSocketSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	signer, err := NewCryptoSigner(key)
+	assert.Nil(t, err)
+	d := newFakeSigningDaemon(t, map[string]Signer{"form3": signer})
+
+	s := &SocketSigner{Path: d.path, Key: "form3", SignatureAlgorithm: AlgorithmECDSASHA256}
+	sig, err := s.Sign([]byte("data"))
+	assert.Nil(t, err)
+	assert.Nil(t, verifySignature(key.Public(), AlgorithmECDSASHA256, []byte("data"), sig))
+
+	// daemon errors are returned
+	s.Key = "unknown"
+	_, err = s.Sign([]byte("data"))
+	assert.EqualError(t, err, "socket signer: unknown key unknown")
+	s.Key, s.SignatureAlgorithm = "form3", AlgorithmRSASHA256
+	_, err = s.Sign([]byte("data"))
+	assert.NotNil(t, err)
+
+	// daemon not running
+	_, err = (&SocketSigner{Path: d.path + ".missing"}).Sign([]byte("data"))
+	assert.NotNil(t, err)
+
+	// daemon not responding
+	ln, err := net.Listen("unix", d.path+".hang")
+	assert.Nil(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			_, _ = io.Copy(ioutil.Discard, conn)
+		}
+	}()
+	_, err = (&SocketSigner{Path: d.path + ".hang", Timeout: 10 * time.Millisecond}).Sign([]byte("data"))
+	assert.NotNil(t, err)
+}
+
+func TestClientExternalSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err)
+	signer, err := NewCryptoSigner(key)
+	assert.Nil(t, err)
+	d := newFakeSigningDaemon(t, map[string]Signer{"form3": signer})
+
+	v := &Verifier{Keys: StaticKeyStore{"test-key": key.Public()}}
+	ts := httptest.NewServer(v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{}}`))
+	})))
+	defer ts.Close()
+
+	// opaque crypto.Signer, e.g. PKCS#11 or KMS key
+	client, err := NewClient(WithBaseURL(ts.URL), WithKeyID("test-key"), WithCryptoSigner(opaqueSigner{key}))
+	assert.Nil(t, err)
+	assert.Equal(t, AlgorithmECDSASHA384, client.signer.Algorithm())
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+
+	// signing daemon
+	client, err = NewClient(WithBaseURL(ts.URL), WithKeyID("test-key"),
+		WithSigner(&SocketSigner{Path: d.path, Key: "form3", SignatureAlgorithm: AlgorithmECDSASHA384}))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("ad27e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+
+	// unsupported key
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	assert.Nil(t, err)
+	_, err = NewClient(WithBaseURL(ts.URL), WithCryptoSigner(opaqueSigner{p224}))
+	assert.NotNil(t, err)
+}
+
+// opaqueSigner hides concrete key type like HSM backed crypto.Signer does.
+type opaqueSigner struct {
+	crypto.Signer
+}
@@ -0,0 +1,247 @@
+package form3go
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// tlsOptions are TLS options applied to HTTP client transport by
+// NewClient.
+type tlsOptions struct {
+	rootCAs    *x509.CertPool
+	caFile     string
+	certs      []tls.Certificate
+	certFile   string
+	keyFile    string
+	minVersion uint16
+	pins       [][]byte
+}
+
+// WithRootCAs sets root certificate authorities used for verifying
+// server certificates instead of system ones.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Client) error {
+		c.tls().rootCAs = pool
+		return nil
+	}
+}
+
+// WithCAFile sets path of PEM encoded CA bundle used for verifying server
+// certificates instead of system root certificate authorities.
+func WithCAFile(path string) Option {
+	return func(c *Client) error {
+		c.tls().caFile = path
+		return nil
+	}
+}
+
+// WithClientCertificate sets certificate presented to server for mutual
+// TLS.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *Client) error {
+		c.tls().certs = append(c.tls().certs, cert)
+		return nil
+	}
+}
+
+// WithClientCertificateFile sets PEM encoded certificate and private key
+// files presented to server for mutual TLS. Files are loaded by NewClient
+// and loaded again on TLS handshake when either of them changed, so
+// renewed certificates are picked up without restarting.
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	return func(c *Client) error {
+		if certFile == "" || keyFile == "" {
+			return errors.New("form3go: client certificate and key files are required")
+		}
+		c.tls().certFile, c.tls().keyFile = certFile, keyFile
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets minimum TLS version, e.g. tls.VersionTLS13.
+// TLS 1.2 is used by default.
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *Client) error {
+		if version < tls.VersionTLS12 || version > tls.VersionTLS13 {
+			return fmt.Errorf("form3go: unsupported TLS version %#x", version)
+		}
+		c.tls().minVersion = version
+		return nil
+	}
+}
+
+// WithPinnedSPKI pins server certificate chain to given base64 encoded
+// SHA-256 hashes of Subject Public Key Info, as used by pin-sha256 of
+// HPKP. Connection is accepted only if a certificate of the chain
+// verified against root CAs matches one of the pins. Certificates sent
+// by server but not part of verified chain are not considered.
+func WithPinnedSPKI(hashes ...string) Option {
+	return func(c *Client) error {
+		for _, h := range hashes {
+			pin, err := base64.StdEncoding.DecodeString(h)
+			if err != nil || len(pin) != sha256.Size {
+				return fmt.Errorf("form3go: invalid SPKI pin %q", h)
+			}
+			c.tls().pins = append(c.tls().pins, pin)
+		}
+		return nil
+	}
+}
+
+// SPKIHash returns base64 encoded SHA-256 hash of Subject Public Key Info
+// of cert as accepted by WithPinnedSPKI.
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// tls returns TLS options of client.
+func (c *Client) tls() *tlsOptions {
+	if c.tlsOpts == nil {
+		c.tlsOpts = &tlsOptions{}
+	}
+	return c.tlsOpts
+}
+
+// config returns TLS config of options.
+func (o *tlsOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      o.rootCAs,
+		Certificates: o.certs,
+	}
+	if o.minVersion != 0 {
+		cfg.MinVersion = o.minVersion
+	}
+	if o.caFile != "" {
+		pem, err := ioutil.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("form3go: loading CA file: %v", err)
+		}
+		if cfg.RootCAs == nil {
+			cfg.RootCAs = x509.NewCertPool()
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("form3go: no certificates found in CA file %s", o.caFile)
+		}
+	}
+	if o.certFile != "" {
+		cf, err := newClientCertFile(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("form3go: loading client certificate: %v", err)
+		}
+		cfg.GetClientCertificate = cf.getClientCertificate
+	}
+	if len(o.pins) > 0 {
+		pins := o.pins
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			// PeerCertificates are as sent by server, which may append any
+			// public certificate, so only verified chains are checked
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					for _, pin := range pins {
+						if subtle.ConstantTimeCompare(sum[:], pin) == 1 {
+							return nil
+						}
+					}
+				}
+			}
+			return errors.New("form3go: server certificate does not match pinned SPKI")
+		}
+	}
+	return cfg, nil
+}
+
+// applyTLS applies TLS options to HTTP client of c. HTTP client given to
+// WithHTTPClient is copied rather than modified.
+func (c *Client) applyTLS() error {
+	if c.tlsOpts == nil {
+		return nil
+	}
+	cfg, err := c.tlsOpts.config()
+	if err != nil {
+		return err
+	}
+	var transport *http.Transport
+	switch t := c.httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return fmt.Errorf("form3go: TLS options require *http.Transport, got %T", t)
+	}
+	transport.TLSClientConfig = cfg
+	hc := *c.httpClient
+	hc.Transport = transport
+	c.httpClient = &hc
+	return nil
+}
+
+// clientCertFile is client certificate loaded from files, reloaded when
+// they change.
+type clientCertFile struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+func newClientCertFile(certFile, keyFile string) (*clientCertFile, error) {
+	cf := &clientCertFile{certFile: certFile, keyFile: keyFile}
+	modTimes, err := cf.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := cf.load(modTimes); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// stat returns modification times of certificate and key files.
+func (cf *clientCertFile) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{cf.certFile, cf.keyFile} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (cf *clientCertFile) load(modTimes [2]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(cf.certFile, cf.keyFile)
+	if err != nil {
+		return err
+	}
+	cf.cert = &cert
+	cf.modTimes = modTimes
+	return nil
+}
+
+// getClientCertificate returns current client certificate, reloading it
+// first if files changed. If changed files cannot be loaded, e.g. while
+// they are being written, previous certificate is returned.
+func (cf *clientCertFile) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+	if modTimes, err := cf.stat(); err == nil && modTimes != cf.modTimes {
+		_ = cf.load(modTimes)
+	}
+	return cf.cert, nil
+}
@@ -0,0 +1,251 @@
+package form3go
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert is generated certificate with its PEM encoding.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func (tc testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(tc.certPEM, tc.keyPEM)
+	assert.Nil(t, err)
+	return cert
+}
+
+// genTestCert generates certificate of cn signed by parent, or self
+// signed CA certificate if parent is nil.
+func genTestCert(t *testing.T, cn string, serial int64, parent *testCert) testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signerCert, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signerCert, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// roundTripperFunc adapts function to http.RoundTripper.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTLSTestClient(t *testing.T, url string, opts ...Option) (*Client, error) {
+	return NewClient(append([]Option{
+		WithBaseURL(url),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithPrivateKeyPath("../test_private_key.pem"),
+	}, opts...)...)
+}
+
+func TestClientPinnedSPKIChain(t *testing.T) {
+	ca := genTestCert(t, "form3go test CA", 1, nil)
+	leaf := genTestCert(t, "server", 2, &ca)
+	pinned := genTestCert(t, "pinned", 3, &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	// server presents pinned certificate after its own leaf, which does
+	// not make it part of verified chain
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	cert := leaf.tlsCertificate(t)
+	cert.Certificate = append(cert.Certificate, pinned.cert.Raw)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		pin  string
+		ok   bool
+	}{
+		{"pinned leaf", SPKIHash(leaf.cert), true},
+		{"pinned CA", SPKIHash(ca.cert), true},
+		{"pinned unverified certificate", SPKIHash(pinned.cert), false},
+	}
+	for _, tt := range tests {
+		client, err := newTLSTestClient(t, srv.URL, WithRootCAs(pool), WithPinnedSPKI(tt.pin))
+		assert.Nil(t, err, tt.name)
+		_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+		assert.Equal(t, tt.ok, err == nil, "%s: %v", tt.name, err)
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	dir, err := ioutil.TempDir("", "form3go")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600))
+
+	tests := []struct {
+		name string
+		opts []Option
+		ok   bool
+	}{
+		{"unknown authority", nil, false},
+		{"root CAs", []Option{WithRootCAs(pool)}, true},
+		{"CA file", []Option{WithCAFile(caFile)}, true},
+		{"pinned SPKI", []Option{WithRootCAs(pool), WithPinnedSPKI(SPKIHash(srv.Certificate()))}, true},
+		{"other pinned SPKI", []Option{WithRootCAs(pool), WithPinnedSPKI("47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=")}, false},
+		{"TLS 1.3", []Option{WithRootCAs(pool), WithMinTLSVersion(tls.VersionTLS13)}, true},
+	}
+	for _, tt := range tests {
+		client, err := newTLSTestClient(t, srv.URL, tt.opts...)
+		assert.Nil(t, err, tt.name)
+		_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+		assert.Equal(t, tt.ok, err == nil, "%s: %v", tt.name, err)
+	}
+
+	// invalid options
+	for _, opt := range []Option{
+		WithCAFile(filepath.Join(dir, "missing.pem")),
+		WithCAFile("../test_private_key.pem"),
+		WithPinnedSPKI("invalid"),
+		WithMinTLSVersion(tls.VersionTLS10),
+		WithClientCertificateFile("", ""),
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(nil)}),
+	} {
+		_, err := newTLSTestClient(t, srv.URL, opt, WithRootCAs(pool))
+		assert.NotNil(t, err)
+	}
+
+	// minimum version is enforced
+	old := httptest.NewUnstartedServer(srv.Config.Handler)
+	old.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	old.StartTLS()
+	defer old.Close()
+	pool.AddCert(old.Certificate())
+	client, err := newTLSTestClient(t, old.URL, WithRootCAs(pool), WithMinTLSVersion(tls.VersionTLS13))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.NotNil(t, err)
+
+	// HTTP client given to WithHTTPClient is not modified
+	hc := &http.Client{Timeout: time.Minute}
+	client, err = newTLSTestClient(t, srv.URL, WithHTTPClient(hc), WithRootCAs(pool))
+	assert.Nil(t, err)
+	assert.Nil(t, hc.Transport)
+	assert.Equal(t, time.Minute, client.httpClient.Timeout)
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	ca := genTestCert(t, "form3go test CA", 1, nil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	var serial int64
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serial = r.TLS.PeerCertificates[0].SerialNumber.Int64()
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	// client certificate is required
+	client, err := newTLSTestClient(t, srv.URL, WithRootCAs(pool))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.NotNil(t, err)
+
+	client, err = newTLSTestClient(t, srv.URL, WithRootCAs(pool), WithClientCertificate(genTestCert(t, "client", 2, &ca).tlsCertificate(t)))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), serial)
+
+	// certificate files are reloaded on change
+	dir, err := ioutil.TempDir("", "form3go")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writeCert := func(tc testCert, modTime time.Time) {
+		assert.Nil(t, ioutil.WriteFile(certFile, tc.certPEM, 0600))
+		assert.Nil(t, ioutil.WriteFile(keyFile, tc.keyPEM, 0600))
+		assert.Nil(t, os.Chtimes(certFile, modTime, modTime))
+		assert.Nil(t, os.Chtimes(keyFile, modTime, modTime))
+	}
+	writeCert(genTestCert(t, "client", 3, &ca), time.Now().Add(-time.Hour))
+
+	_, err = newTLSTestClient(t, srv.URL, WithClientCertificateFile(certFile, filepath.Join(dir, "missing.key")))
+	assert.NotNil(t, err)
+	client, err = newTLSTestClient(t, srv.URL, WithRootCAs(pool), WithClientCertificateFile(certFile, keyFile))
+	assert.Nil(t, err)
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), serial)
+
+	writeCert(genTestCert(t, "client", 4, &ca), time.Now())
+	client.httpClient.CloseIdleConnections()
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), serial)
+
+	// broken files keep previous certificate
+	assert.Nil(t, ioutil.WriteFile(keyFile, []byte("partial"), 0600))
+	client.httpClient.CloseIdleConnections()
+	_, err = client.FetchAccount("9127e265-9605-4b4b-a0e5-3003ea9cc4dc")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), serial)
+}
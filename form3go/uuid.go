@@ -0,0 +1,165 @@
+package form3go
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultIDVersions are UUID versions of account ID accepted by
+// Account.Validate: random version 4, name based version 5, see
+// AccountIDFromKey, and time ordered version 7.
+var DefaultIDVersions = []int{4, 5, 7}
+
+// uuid is RFC 9562 UUID.
+type uuid [16]byte
+
+// parseUUID parses UUID in canonical 8-4-4-4-12 hex form.
+func parseUUID(s string) (uuid, error) {
+	var u uuid
+	if s == "" {
+		return u, fmt.Errorf("UUID is empty")
+	}
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("%q is not UUID in 8-4-4-4-12 hex digits form", s)
+	}
+	digits := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, fmt.Errorf("%q is not UUID in 8-4-4-4-12 hex digits form", s)
+	}
+	return u, nil
+}
+
+// validateUUID checks s is RFC 9562 UUID of one of versions, or of any
+// version if versions are empty.
+func validateUUID(s string, versions []int) error {
+	u, err := parseUUID(s)
+	if err != nil {
+		return err
+	}
+	if u[8]&0xc0 != 0x80 {
+		return fmt.Errorf("UUID %q is not of RFC 9562 variant", s)
+	}
+	version := int(u[6] >> 4)
+	if len(versions) == 0 {
+		if version < 1 || version > 8 {
+			return fmt.Errorf("UUID %q has unknown version %d", s, version)
+		}
+		return nil
+	}
+	accepted := make([]string, len(versions))
+	for i, v := range versions {
+		if v == version {
+			return nil
+		}
+		accepted[i] = strconv.Itoa(v)
+	}
+	return fmt.Errorf("UUID %q is version %d, accepted versions are %s", s, version, strings.Join(accepted, ", "))
+}
+
+// String returns u in canonical lower case form.
+func (u uuid) String() string {
+	var buf [36]byte
+	hex.Encode(buf[:8], u[:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// setVersion sets version and RFC 9562 variant bits of u.
+func (u *uuid) setVersion(version byte) {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+}
+
+// NewAccountID returns random UUID version 4 account ID.
+func NewAccountID() (string, error) {
+	var u uuid
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("form3go: generating account ID: %v", err)
+	}
+	u.setVersion(4)
+	return u.String(), nil
+}
+
+// NewAccountIDV7 returns UUID version 7 account ID, which starts with
+// current Unix time in milliseconds followed by counter and random bits,
+// so IDs generated later by the process sort after earlier ones, also
+// within the same millisecond.
+func NewAccountIDV7() (string, error) {
+	return defaultV7.next(time.Now())
+}
+
+// defaultV7 is generator of NewAccountIDV7.
+var defaultV7 v7Generator
+
+// v7Generator generates UUIDs version 7 with 12 bit counter in rand_a
+// field, per RFC 9562 section 6.2 method 1.
+type v7Generator struct {
+	mu  sync.Mutex
+	ms  uint64
+	seq uint16
+}
+
+// next returns UUID version 7 of time now, ordered after previous ones.
+func (g *v7Generator) next(now time.Time) (string, error) {
+	var u uuid
+	if _, err := rand.Read(u[6:]); err != nil {
+		return "", fmt.Errorf("form3go: generating account ID: %v", err)
+	}
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+
+	g.mu.Lock()
+	if ms > g.ms {
+		// counter starts at random value with top bit clear, leaving room
+		// for at least 2048 IDs per millisecond
+		g.ms, g.seq = ms, binary.BigEndian.Uint16(u[6:8])&0x7ff
+	} else {
+		// same millisecond or clock moved backwards, on counter overflow
+		// timestamp is advanced instead
+		g.seq++
+		if g.seq > 0xfff {
+			g.ms, g.seq = g.ms+1, 0
+		}
+	}
+	ms, seq := g.ms, g.seq
+	g.mu.Unlock()
+
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(u[:6], ts[2:])
+	binary.BigEndian.PutUint16(u[6:8], seq)
+	u.setVersion(7)
+	return u.String(), nil
+}
+
+// AccountIDFromKey returns account ID derived from business key, e.g.
+// customer reference, as UUID version 5 in namespace UUID, e.g.
+// organisation ID. The same key always yields the same ID, so repeated
+// creation of account of the key is detected as conflict, see
+// WithIdempotentCreate.
+func AccountIDFromKey(namespace, key string) (string, error) {
+	ns, err := parseUUID(namespace)
+	if err != nil {
+		return "", fmt.Errorf("form3go: invalid namespace: %v", err)
+	}
+	h := sha1.New()
+	h.Write(ns[:])
+	h.Write([]byte(key))
+	var u uuid
+	copy(u[:], h.Sum(nil))
+	u.setVersion(5)
+	return u.String(), nil
+}
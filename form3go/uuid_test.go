@@ -0,0 +1,107 @@
+package form3go
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountIDFromKey(t *testing.T) {
+	// RFC 4122 DNS namespace test vector
+	id, err := AccountIDFromKey("6ba7b810-9dad-11d1-80b4-00c04fd430c8", "python.org")
+	assert.Nil(t, err)
+	assert.Equal(t, "886313e1-3b8a-5372-9b90-0c9aee199e5d", id)
+
+	// IDs are deterministic and pass account validation
+	orgID := "db0bd6f5-c3f5-44b2-b677-acd23cdde73c"
+	id, err = AccountIDFromKey(orgID, "customer-42")
+	assert.Nil(t, err)
+	again, _ := AccountIDFromKey(orgID, "customer-42")
+	other, _ := AccountIDFromKey(orgID, "customer-43")
+	assert.Equal(t, id, again)
+	assert.NotEqual(t, id, other)
+	account := newBulkAccounts(t, 1)[0]
+	account.AccountData.ID = id
+	assert.Nil(t, account.Validate())
+
+	for _, ns := range []string{"", "db0bd6f5c3f544b2b677acd23cdde73c", "db0bd6f5-c3f5-44b2-b677-acd23cdde73z"} {
+		_, err = AccountIDFromKey(ns, "customer-42")
+		assert.NotNil(t, err, ns)
+	}
+}
+
+func TestNewAccountID(t *testing.T) {
+	id, err := NewAccountID()
+	assert.Nil(t, err)
+	assert.Nil(t, validateUUID(id, []int{4}))
+	other, _ := NewAccountID()
+	assert.NotEqual(t, id, other)
+
+	id, err = NewAccountIDV7()
+	assert.Nil(t, err)
+	assert.Nil(t, validateUUID(id, []int{7}))
+
+	// version 7 IDs are ordered by time
+	var g v7Generator
+	now := time.Date(2021, 4, 20, 10, 15, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		id, err := g.next(now.Add(time.Duration(i) * time.Millisecond))
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+	// 1618913700000 ms since Unix epoch
+	assert.Equal(t, "0178eec6-a8a0-7", ids[0][:15])
+
+	// and by counter within the same millisecond or when clock moves
+	// backwards
+	last := now.Add(4 * time.Millisecond)
+	for i := 0; i < 5000; i++ {
+		at := last
+		if i%2 == 1 {
+			at = now
+		}
+		id, err := g.next(at)
+		assert.Nil(t, err)
+		ids = append(ids, id)
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+	for _, id := range ids {
+		assert.Nil(t, validateUUID(id, []int{7}))
+	}
+	// counter overflow advances timestamp
+	assert.NotEqual(t, ids[4][:13], ids[len(ids)-1][:13])
+}
+
+func TestValidateUUID(t *testing.T) {
+	tests := []struct {
+		uuid     string
+		versions []int
+		err      string
+	}{
+		{"9127e265-9605-4b4b-a0e5-3003ea9cc4dc", nil, ""},
+		{"9127E265-9605-4B4B-B0E5-3003EA9CC4DC", []int{4}, ""},
+		{"886313e1-3b8a-5372-9b90-0c9aee199e5d", []int{4, 5}, ""},
+		{"9127e265-9605-1b4b-80e5-3003ea9cc4dc", nil, ""},
+		{"", nil, "UUID is empty"},
+		{"9127e2659605-4b4b-a0e5-3003ea9cc4dc0", nil, `"9127e2659605-4b4b-a0e5-3003ea9cc4dc0" is not UUID in 8-4-4-4-12 hex digits form`},
+		{"9127e265-9605-4b4b-a0e5-3003ea9cc4dx", nil, `"9127e265-9605-4b4b-a0e5-3003ea9cc4dx" is not UUID in 8-4-4-4-12 hex digits form`},
+		// variant characters which buggy [8|9|aA|bB] class accepted
+		{"9127e265-9605-4b4b-|0e5-3003ea9cc4dc", nil, `"9127e265-9605-4b4b-|0e5-3003ea9cc4dc" is not UUID in 8-4-4-4-12 hex digits form`},
+		{"9127e265-9605-4b4b-70e5-3003ea9cc4dc", nil, `UUID "9127e265-9605-4b4b-70e5-3003ea9cc4dc" is not of RFC 9562 variant`},
+		{"00000000-0000-0000-0000-000000000000", nil, `UUID "00000000-0000-0000-0000-000000000000" is not of RFC 9562 variant`},
+		{"9127e265-9605-0b4b-a0e5-3003ea9cc4dc", nil, `UUID "9127e265-9605-0b4b-a0e5-3003ea9cc4dc" has unknown version 0`},
+		{"9127e265-9605-7b4b-a0e5-3003ea9cc4dc", []int{4}, `UUID "9127e265-9605-7b4b-a0e5-3003ea9cc4dc" is version 7, accepted versions are 4`},
+	}
+	for _, tt := range tests {
+		err := validateUUID(tt.uuid, tt.versions)
+		if tt.err == "" {
+			assert.Nil(t, err, tt.uuid)
+		} else if assert.NotNil(t, err, tt.uuid) {
+			assert.Equal(t, tt.err, err.Error())
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package form3go
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSignatureMissing is matched by error returned by Verifier when
+	// request carries no Authorization signature.
+	ErrSignatureMissing = errors.New("form3go: signature missing")
+
+	// ErrSignatureInvalid is matched by error returned by Verifier when
+	// request signature is malformed or does not verify.
+	ErrSignatureInvalid = errors.New("form3go: invalid signature")
+
+	// ErrDigestMismatch is matched by error returned by Verifier when
+	// Digest header does not match request body.
+	ErrDigestMismatch = errors.New("form3go: digest mismatch")
+
+	// ErrClockSkew is matched by error returned by Verifier when Date
+	// header is outside of allowed clock skew window.
+	ErrClockSkew = errors.New("form3go: date outside of allowed clock skew")
+
+	// ErrUnknownKey is matched by error returned by Verifier when public
+	// key of keyId cannot be resolved.
+	ErrUnknownKey = errors.New("form3go: unknown key")
+
+	// ErrBodyTooLarge is matched by error returned by Verifier when
+	// request body exceeds MaxBodySize.
+	ErrBodyTooLarge = errors.New("form3go: request body too large")
+)
+
+const (
+	// DefaultMaxClockSkew is clock skew allowed by Verifier when MaxSkew
+	// is not provided.
+	DefaultMaxClockSkew = 5 * time.Minute
+
+	// DefaultMaxBodySize is size of request body read by Verifier for
+	// digest check when MaxBodySize is not provided.
+	DefaultMaxBodySize = 10 << 20
+)
+
+// KeyStore resolves public keys by key ID.
+type KeyStore interface {
+	PublicKey(keyID string) (crypto.PublicKey, error)
+}
+
+// KeyStoreFunc adapts function to KeyStore.
+type KeyStoreFunc func(keyID string) (crypto.PublicKey, error)
+
+// PublicKey calls f(keyID).
+func (f KeyStoreFunc) PublicKey(keyID string) (crypto.PublicKey, error) {
+	return f(keyID)
+}
+
+// StaticKeyStore is KeyStore of fixed public keys keyed by key ID.
+type StaticKeyStore map[string]crypto.PublicKey
+
+// PublicKey returns public key of keyID.
+func (s StaticKeyStore) PublicKey(keyID string) (crypto.PublicKey, error) {
+	key, ok := s[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// Verifier verifies requests signed per draft-cavage-http-signatures or
+// RFC 9421 HTTP Message Signatures, the counterpart of Client request
+// signing.
+type Verifier struct {
+	// Keys resolves public keys by keyId of Authorization header.
+	Keys KeyStore
+	// MaxSkew is allowed difference between Date header and current
+	// time, DefaultMaxClockSkew is used when it is zero.
+	MaxSkew time.Duration
+	// RequiredHeaders must be covered by draft-cavage signature.
+	// (request-target), host and date are required when it is empty.
+	// digest or content-digest is always required for requests with body.
+	RequiredHeaders []string
+	// RequiredComponents must be covered by RFC 9421 signature. @method,
+	// @authority and @path are required when it is empty. content-digest
+	// is always required for requests with body.
+	RequiredComponents []string
+	// MaxBodySize is maximum size of request body read for digest check,
+	// DefaultMaxBodySize is used when it is zero. Larger requests are
+	// rejected with ErrBodyTooLarge.
+	MaxBodySize int64
+	// Now returns current time, time.Now is used when it is nil.
+	Now func() time.Time
+}
+
+// signatureParams are parameters of Authorization Signature header.
+type signatureParams struct {
+	keyID     string
+	algorithm string
+	headers   []string
+	signature []byte
+}
+
+// Verify verifies signature, Date and Digest headers of req and returns
+// keyId it is signed with. Request body is read only after signature is
+// verified, and it is restored so it can be read again.
+func (v *Verifier) Verify(req *http.Request) (string, error) {
+	if input := req.Header.Get("Signature-Input"); input != "" {
+		return v.verifyMessage(req, input)
+	}
+	params, err := parseSignatureHeader(req.Header.Get("Authorization"))
+	if err != nil {
+		return "", err
+	}
+
+	// check signed headers
+	required := v.RequiredHeaders
+	if len(required) == 0 {
+		required = []string{"(request-target)", "host", "date"}
+	}
+	for _, h := range required {
+		if !containsString(params.headers, h) {
+			return params.keyID, fmt.Errorf("%w: %s header is not signed", ErrSignatureInvalid, h)
+		}
+	}
+	if err := v.verifyDate(req); err != nil {
+		return params.keyID, err
+	}
+
+	// check signature
+	if v.Keys == nil {
+		return params.keyID, ErrUnknownKey
+	}
+	key, err := v.Keys.PublicKey(params.keyID)
+	if err != nil {
+		return params.keyID, fmt.Errorf("%w: %s: %v", ErrUnknownKey, params.keyID, err)
+	}
+	signatureStr, err := signingString(req, params.headers)
+	if err != nil {
+		return params.keyID, fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	if err := verifySignature(key, params.algorithm, []byte(signatureStr), params.signature); err != nil {
+		return params.keyID, fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	return params.keyID, v.verifyDigest(req, params.headers)
+}
+
+// verifyMessage verifies RFC 9421 signature of req described by
+// Signature-Input header input.
+func (v *Verifier) verifyMessage(req *http.Request, input string) (string, error) {
+	ms, err := parseMessageSignature(input, req.Header.Get("Signature"))
+	if err != nil {
+		return ms.keyID, err
+	}
+
+	// check signed components
+	required := v.RequiredComponents
+	if len(required) == 0 {
+		required = []string{"@method", "@authority", "@path"}
+	}
+	if req.URL.RawQuery != "" {
+		required = append(required[:len(required):len(required)], "@query")
+	}
+	for _, c := range required {
+		if !containsString(ms.components, c) {
+			return ms.keyID, fmt.Errorf("%w: %s component is not signed", ErrSignatureInvalid, c)
+		}
+	}
+	if !ms.hasCreated {
+		return ms.keyID, fmt.Errorf("%w: created parameter is required", ErrSignatureInvalid)
+	}
+	if err := v.checkSkew(time.Unix(ms.created, 0)); err != nil {
+		return ms.keyID, err
+	}
+
+	// check signature
+	if v.Keys == nil {
+		return ms.keyID, ErrUnknownKey
+	}
+	key, err := v.Keys.PublicKey(ms.keyID)
+	if err != nil {
+		return ms.keyID, fmt.Errorf("%w: %s: %v", ErrUnknownKey, ms.keyID, err)
+	}
+	algorithm := keyAlgorithm(key)
+	if ms.alg != "" && messageSignatureAlgorithms[algorithm] != ms.alg {
+		return ms.keyID, fmt.Errorf("%w: key of type %T cannot verify %s", ErrSignatureInvalid, key, ms.alg)
+	}
+	sig := ms.signature
+	if size := ecdsaKeySize(algorithm); size > 0 {
+		if sig, err = ecdsaDERSignature(sig, size); err != nil {
+			return ms.keyID, fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+		}
+	}
+	base, err := signatureBase(req, ms.components, ms.params)
+	if err != nil {
+		return ms.keyID, fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	if err := verifySignature(key, algorithm, []byte(base), sig); err != nil {
+		return ms.keyID, fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	return ms.keyID, v.verifyDigest(req, ms.components)
+}
+
+// Handler returns handler verifying requests before passing them to next.
+// Requests failing verification are rejected with 401 Unauthorized, or
+// 413 Request Entity Too Large if body exceeds MaxBodySize.
+func (v *Verifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := v.Verify(r); err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verifyDate checks Date header is within allowed clock skew. Only HTTP
+// dates in GMT are accepted, as time.Parse would treat unknown zone
+// abbreviations like EST as UTC.
+func (v *Verifier) verifyDate(req *http.Request) error {
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: invalid date header %q", ErrClockSkew, req.Header.Get("Date"))
+	}
+	return v.checkSkew(date)
+}
+
+// checkSkew checks date is within allowed clock skew.
+func (v *Verifier) checkSkew(date time.Time) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	maxSkew := v.MaxSkew
+	if maxSkew == 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+	if skew := now.Sub(date); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("%w: date is %v off", ErrClockSkew, skew)
+	}
+	return nil
+}
+
+// verifyDigest checks Digest or Content-Digest header matches request
+// body of at most v.MaxBodySize bytes. Requests with body must sign the
+// digest.
+func (v *Verifier) verifyDigest(req *http.Request, signed []string) error {
+	var data []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		maxSize := v.MaxBodySize
+		if maxSize == 0 {
+			maxSize = DefaultMaxBodySize
+		}
+		var err error
+		data, err = ioutil.ReadAll(http.MaxBytesReader(nil, req.Body, maxSize))
+		req.Body.Close()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxSize)
+		}
+		if err != nil {
+			return err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	digest, contentDigest := req.Header.Get("Digest"), req.Header.Get("Content-Digest")
+	if len(data) == 0 && digest == "" && contentDigest == "" {
+		return nil
+	}
+	switch {
+	case contentDigest != "" && containsString(signed, "content-digest"):
+		return checkDigest(contentDigest, data, true)
+	case digest != "" && containsString(signed, "digest"):
+		return checkDigest(digest, data, false)
+	}
+	return fmt.Errorf("%w: digest header is not signed", ErrSignatureInvalid)
+}
+
+// checkDigest checks the first SHA-256 or SHA-512 digest of Digest header
+// value v, or RFC 9530 Content-Digest one if structured, matches data.
+func checkDigest(v string, data []byte, structured bool) error {
+	for _, d := range strings.Split(v, ",") {
+		d = strings.TrimSpace(d)
+		i := strings.IndexByte(d, '=')
+		if i < 0 {
+			continue
+		}
+		algorithm := strings.ToUpper(d[:i])
+		if algorithm != DigestSHA256 && algorithm != DigestSHA512 {
+			continue
+		}
+		value := d[i+1:]
+		if structured {
+			if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+				return fmt.Errorf("%w: malformed content-digest %q", ErrDigestMismatch, d)
+			}
+			value = value[1 : len(value)-1]
+		}
+		expected := base64.StdEncoding.EncodeToString(digestSum(algorithm, data))
+		if subtle.ConstantTimeCompare([]byte(value), []byte(expected)) == 1 {
+			return nil
+		}
+		return ErrDigestMismatch
+	}
+	return fmt.Errorf("%w: no supported digest in %q", ErrDigestMismatch, v)
+}
+
+// verifySignature verifies sig of data with public key using algorithm.
+// Algorithm is derived from key type when it is empty.
+func verifySignature(key crypto.PublicKey, algorithm string, data, sig []byte) error {
+	if algorithm == "" {
+		algorithm = keyAlgorithm(key)
+	}
+	switch algorithm {
+	case AlgorithmRSASHA256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key of type %T cannot verify %s", key, algorithm)
+		}
+		hash := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig)
+	case AlgorithmECDSASHA256, AlgorithmECDSASHA384:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || keyAlgorithm(pub) != algorithm {
+			return fmt.Errorf("key of type %T cannot verify %s", key, algorithm)
+		}
+		hash := crypto.SHA256
+		if algorithm == AlgorithmECDSASHA384 {
+			hash = crypto.SHA384
+		}
+		if !ecdsa.VerifyASN1(pub, hashData(hash, data), sig) {
+			return errors.New("ecdsa verification failure")
+		}
+		return nil
+	case AlgorithmEd25519:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key of type %T cannot verify %s", key, algorithm)
+		}
+		if !ed25519.Verify(pub, data, sig) {
+			return errors.New("ed25519 verification failure")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+}
+
+// keyAlgorithm returns signature algorithm of public key, or empty string
+// if key is not supported.
+func keyAlgorithm(key crypto.PublicKey) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return AlgorithmRSASHA256
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return AlgorithmECDSASHA256
+		case elliptic.P384():
+			return AlgorithmECDSASHA384
+		}
+	case ed25519.PublicKey:
+		return AlgorithmEd25519
+	}
+	return ""
+}
+
+// parseSignatureHeader parses Authorization header of the form
+// Signature keyId="...",algorithm="...",headers="...",signature="...".
+func parseSignatureHeader(v string) (signatureParams, error) {
+	const scheme = "Signature "
+	if len(v) < len(scheme) || !strings.EqualFold(v[:len(scheme)], scheme) {
+		return signatureParams{}, ErrSignatureMissing
+	}
+
+	params := map[string]string{}
+	rest := strings.TrimSpace(v[len(scheme):])
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 || len(rest) < eq+2 || rest[eq+1] != '"' {
+			return signatureParams{}, fmt.Errorf("%w: malformed Authorization header", ErrSignatureInvalid)
+		}
+		name := strings.TrimSpace(rest[:eq])
+		rest = rest[eq+2:]
+		end := strings.IndexByte(rest, '"')
+		if end < 0 {
+			return signatureParams{}, fmt.Errorf("%w: malformed Authorization header", ErrSignatureInvalid)
+		}
+		params[name] = rest[:end]
+		rest = strings.TrimSpace(rest[end+1:])
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	}
+
+	p := signatureParams{
+		keyID:     params["keyId"],
+		algorithm: params["algorithm"],
+	}
+	if p.keyID == "" || params["signature"] == "" {
+		return signatureParams{}, fmt.Errorf("%w: keyId and signature are required", ErrSignatureInvalid)
+	}
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return signatureParams{}, fmt.Errorf("%w: signature is not base64: %v", ErrSignatureInvalid, err)
+	}
+	p.signature = sig
+	// date is the only signed header when headers parameter is omitted
+	p.headers = []string{"date"}
+	if h := params["headers"]; h != "" {
+		p.headers = strings.Fields(strings.ToLower(h))
+	}
+	return p, nil
+}
@@ -0,0 +1,199 @@
+package form3go
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestVerifier returns verifier knowing public key of test private key.
+func newTestVerifier(t *testing.T) *Verifier {
+	return &Verifier{
+		Keys: StaticKeyStore{
+			"75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8": &loadTestKey(t).PublicKey,
+		},
+	}
+}
+
+// newSignedRequest returns request signed with test private key as if
+// received by server.
+func newSignedRequest(t *testing.T, method, target, body string) *http.Request {
+	signer := loadTestKey(t)
+	var req *http.Request
+	if body != "" {
+		req, _ = http.NewRequest(method, "http://accountapi:8080"+target, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/vnd.api+json")
+	} else {
+		req, _ = http.NewRequest(method, "http://accountapi:8080"+target, nil)
+	}
+	req.Header.Set("Accept", "application/vnd.api+json")
+	assert.Nil(t, signRequest(req, "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", signer, signOptions{}, time.Now()))
+
+	// turn client request into server request
+	srvReq := httptest.NewRequest(method, target, strings.NewReader(body))
+	srvReq.Host = "accountapi:8080"
+	srvReq.Header = req.Header.Clone()
+	return srvReq
+}
+
+func TestVerify(t *testing.T) {
+	v := newTestVerifier(t)
+
+	// Valid requests
+	req := newSignedRequest(t, "POST", "/v1/organisation/accounts", testAccountInfo)
+	keyID, err := v.Verify(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8", keyID)
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, testAccountInfo, string(body))
+
+	req = newSignedRequest(t, "DELETE", "/v1/organisation/accounts/9127e265-9605-4b4b-a0e5-3003ea9cc4dc?version=0", "")
+	_, err = v.Verify(req)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+		err    error
+	}{
+		{"missing signature", func(req *http.Request) { req.Header.Del("Authorization") }, ErrSignatureMissing},
+		{"tampered body", func(req *http.Request) {
+			req.Body = ioutil.NopCloser(strings.NewReader(strings.Replace(testAccountInfo, "GB", "DE", 1)))
+		}, ErrDigestMismatch},
+		{"tampered digest", func(req *http.Request) {
+			req.Header.Set("Digest", genDigestHeader([]byte("{}")))
+			req.Body = ioutil.NopCloser(strings.NewReader("{}"))
+		}, ErrSignatureInvalid},
+		{"tampered target", func(req *http.Request) { req.URL.Path = "/v1/organisation/accounts/other" }, ErrSignatureInvalid},
+		{"tampered host", func(req *http.Request) { req.Host = "evil:8080" }, ErrSignatureInvalid},
+		{"unknown key", func(req *http.Request) {
+			req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), "75a8ba12", "00000000", 1))
+		}, ErrUnknownKey},
+		{"unsigned digest", func(req *http.Request) {
+			req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), " digest", "", 1))
+		}, ErrSignatureInvalid},
+		{"unsigned host", func(req *http.Request) {
+			req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), " host", "", 1))
+		}, ErrSignatureInvalid},
+		{"stale date", func(req *http.Request) {
+			req.Header.Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		}, ErrClockSkew},
+		{"non-GMT date", func(req *http.Request) {
+			req.Header.Set("Date", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 EST"))
+		}, ErrClockSkew},
+		{"malformed header", func(req *http.Request) { req.Header.Set("Authorization", `Signature keyId="a",signature`) }, ErrSignatureInvalid},
+		{"unsupported algorithm", func(req *http.Request) {
+			req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), "rsa-sha256", "hmac-sha256", 1))
+		}, ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		req := newSignedRequest(t, "POST", "/v1/organisation/accounts", testAccountInfo)
+		tt.tamper(req)
+		_, err := v.Verify(req)
+		assert.True(t, errors.Is(err, tt.err), "%s: %v", tt.name, err)
+	}
+}
+
+func TestVerifyClockSkew(t *testing.T) {
+	v := newTestVerifier(t)
+	req := newSignedRequest(t, "GET", "/v1/organisation/accounts", "")
+	date, _ := time.Parse(time.RFC1123, req.Header.Get("Date"))
+
+	v.Now = func() time.Time { return date.Add(2 * time.Minute) }
+	_, err := v.Verify(req)
+	assert.Nil(t, err)
+
+	v.MaxSkew = time.Minute
+	_, err = v.Verify(req)
+	assert.True(t, errors.Is(err, ErrClockSkew))
+
+	v.Now = func() time.Time { return date.Add(-2 * time.Minute) }
+	_, err = v.Verify(req)
+	assert.True(t, errors.Is(err, ErrClockSkew))
+}
+
+// readCounter counts reads of request body.
+type readCounter struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *readCounter) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func TestVerifyBody(t *testing.T) {
+	v := newTestVerifier(t)
+
+	// body is not read before signature is verified
+	req := newSignedRequest(t, "POST", "/v1/organisation/accounts", testAccountInfo)
+	req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), " digest", "", 1))
+	body := &readCounter{r: strings.NewReader(testAccountInfo)}
+	req.Body = ioutil.NopCloser(body)
+	_, err := v.Verify(req)
+	assert.True(t, errors.Is(err, ErrSignatureInvalid))
+	assert.Equal(t, 0, body.reads)
+
+	// body is read up to MaxBodySize
+	v.MaxBodySize = int64(len(testAccountInfo))
+	req = newSignedRequest(t, "POST", "/v1/organisation/accounts", testAccountInfo)
+	_, err = v.Verify(req)
+	assert.Nil(t, err)
+
+	v.MaxBodySize--
+	req = newSignedRequest(t, "POST", "/v1/organisation/accounts", testAccountInfo)
+	_, err = v.Verify(req)
+	assert.True(t, errors.Is(err, ErrBodyTooLarge), "%v", err)
+
+	rec := httptest.NewRecorder()
+	req = newSignedRequest(t, "POST", "/v1/organisation/accounts", testAccountInfo)
+	v.Handler(http.NotFoundHandler()).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestVerifierHandler(t *testing.T) {
+	v := newTestVerifier(t)
+	v.Keys = KeyStoreFunc(func(keyID string) (crypto.PublicKey, error) {
+		return newTestVerifier(t).Keys.PublicKey(keyID)
+	})
+	var served int
+	srv := httptest.NewServer(v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(testAccountInfo))
+	})))
+	defer srv.Close()
+
+	// Requests signed by Client pass verification
+	client, err := NewClient(
+		WithBaseURL(srv.URL),
+		WithKeyID("75a8ba12-fff2-4a52-ad8a-e8b34c5ccec8"),
+		WithPrivateKeyPath("../test_private_key.pem"),
+	)
+	assert.Nil(t, err)
+	account := &Account{}
+	_ = json.Unmarshal([]byte(testAccountInfo), account)
+	_, err = client.CreateAccount(*account)
+	assert.Nil(t, err)
+	err = client.DeleteAccount(account.AccountData.ID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, served)
+
+	// Unsigned requests are rejected
+	resp, err := http.Get(srv.URL + acctURL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, 2, served)
+}